@@ -0,0 +1,149 @@
+package serror
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/vovanec/serror/internal"
+)
+
+const ctxElapsedKey = "elapsed"
+
+type startTimeKeyType struct{}
+
+var startTimeKey startTimeKeyType
+
+// CancelCauseFunc cancels the context with the cause, see WithCancelCause.
+type CancelCauseFunc func(cause error)
+
+// WithCancelCause is like context.WithCancelCause, but the cancellation cause is
+// a structured error recording where the context was canceled: causes which are not
+// structured errors, including nil standing for context.Canceled, get the origin of
+// the cancel call. The context also records the time it was created at, so
+// FromContext reports the elapsed time. Calls after the context is done are no-ops.
+func WithCancelCause(parent context.Context) (context.Context, CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithValue(parent, startTimeKey, time.Now()))
+	return ctx, func(cause error) {
+		if ctx.Err() != nil {
+			// The cause of the done context can't change, so it is not created.
+			return
+		}
+		cancel(causeError(cause, 3))
+	}
+}
+
+// WithTimeout is like context.WithTimeoutCause, but the cause is the structured
+// error with the message and log args, recording where the timeout was set. Since
+// the cause is created in advance and most timeouts never fire, it is not passed
+// to OnCreate hooks and observers.
+func WithTimeout(parent context.Context, timeout time.Duration, message string, args ...any) (context.Context, context.CancelFunc) {
+	am := make(map[string]slog.Attr)
+	internal.ParseLogArgs(args, func(a slog.Attr) {
+		am[a.Key] = a
+	})
+	origin := getOrigin(2)
+
+	cause := &sError{
+		err:    errors.New(message),
+		msg:    message,
+		attrs:  am,
+		origin: origin,
+		stack:  []Origin{origin},
+	}
+	return context.WithTimeoutCause(context.WithValue(parent, startTimeKey, time.Now()), timeout, cause)
+}
+
+// causeError returns the cause if it is the structured error, or the structured
+// error wrapping it with the origin taken depth stack frames above causeError otherwise.
+func causeError(cause error, depth int) error {
+	if cause == nil {
+		cause = context.Canceled
+	}
+	var sErr *sError
+	if As(cause, &sErr) {
+		return cause
+	}
+	origin := getOrigin(depth)
+	return &sError{
+		err:    cause,
+		msg:    cause.Error(),
+		attrs:  make(map[string]slog.Attr),
+		origin: origin,
+		stack:  []Origin{origin},
+	}
+}
+
+// FromContext returns nil if the context is not done, or the structured error wrapping
+// both the context error and its cancellation cause otherwise, so it matches both with
+// errors.Is. The error carries log attributes of the cause and the context, see
+// SetContextKeys and WithContextKeys, and the "context" group with the context error,
+// deadline and the time elapsed since the context was created by WithCancelCause or WithTimeout.
+func FromContext(ctx context.Context, args ...any) error {
+	ctxErr := ctx.Err()
+	if ctxErr == nil {
+		return nil
+	}
+
+	var (
+		cause = context.Cause(ctx)
+		err   = ctxErr
+	)
+	if errors.Is(cause, ctxErr) {
+		err = cause
+	} else if cause != nil {
+		err = &contextError{err: ctxErr, cause: cause}
+	}
+
+	am := errorArgs(cause, append(contextArgs(ctx, nil, false), args...))
+
+	var (
+		origin Origin
+		stack  []Origin
+		sErr   *sError
+	)
+	if As(cause, &sErr) {
+		origin = sErr.origin
+		stack = sErr.stack[:len(sErr.stack):len(sErr.stack)]
+	}
+	captureStack := runCreateHooks(ctx, ctxErr.Error(), err, args, am)
+	if origin.Empty() {
+		origin = getOrigin(2)
+		stack = []Origin{origin}
+	} else if captureStack {
+		stack = append(stack, getOrigin(2))
+	}
+
+	return observe(ctx, nil, &sError{
+		err:    err,
+		msg:    ctxErr.Error(),
+		attrs:  am,
+		origin: origin,
+		stack:  stack,
+	})
+}
+
+// contextError is the context error with its cancellation cause.
+type contextError struct {
+	err   error
+	cause error
+}
+
+func (e *contextError) Error() string {
+	return e.err.Error() + ": " + e.cause.Error()
+}
+
+func (e *contextError) Unwrap() []error {
+	return []error{e.err, e.cause}
+}
+
+// contextElapsed returns the time elapsed since the context was created
+// by WithCancelCause or WithTimeout.
+func contextElapsed(ctx context.Context) (time.Duration, bool) {
+	start, ok := ctx.Value(startTimeKey).(time.Time)
+	if !ok {
+		return 0, false
+	}
+	return time.Since(start), true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/vovanec/serror"
+)
+
+// Catalog is the declarative description of package errors.
+type Catalog struct {
+	// Package is the name of the generated package.
+	Package string  `json:"package"`
+	Errors  []Entry `json:"errors"`
+}
+
+// Entry describes one error of the catalog.
+type Entry struct {
+	// Code is the value of the "code" log attribute identifying the error.
+	Code string `json:"code"`
+	// Name is the Go name of the constructor, derived from Code if empty.
+	Name string `json:"name"`
+	// Message is the error message template, {name} placeholders are replaced with values
+	// of params, see serror.Newt.
+	Message string `json:"message"`
+	// Params are log attributes passed to the constructor as typed arguments.
+	Params []Param `json:"params"`
+	// Severity is the log level name: debug, info, warn or error.
+	Severity string `json:"severity"`
+	// HTTPStatus is the HTTP status code the error is reported with.
+	HTTPStatus int `json:"http_status"`
+	// PublicMessage is the message safe to show to API clients.
+	PublicMessage string `json:"public_message"`
+	// Description is the documentation of the error.
+	Description string `json:"description"`
+}
+
+// Param is the typed log attribute of the error.
+type Param struct {
+	// Name is the log attribute key.
+	Name string `json:"name"`
+	// Type is the Go type of the attribute value.
+	Type string `json:"type"`
+}
+
+// paramTypes maps supported param types to slog attribute constructors.
+var paramTypes = map[string]string{
+	"string":        "slog.String",
+	"int":           "slog.Int",
+	"int64":         "slog.Int64",
+	"uint64":        "slog.Uint64",
+	"float64":       "slog.Float64",
+	"bool":          "slog.Bool",
+	"time.Duration": "slog.Duration",
+	"time.Time":     "slog.Time",
+	"any":           "slog.Any",
+}
+
+// reservedNames are identifiers used by the generated code, params with these
+// names would shadow them.
+var reservedNames = map[string]bool{
+	"args": true, "any": true, "append": true, "serror": true, "slog": true, "time": true,
+}
+
+// initialisms are upper-cased when param and code names are converted to Go names.
+var initialisms = map[string]bool{
+	"api": true, "db": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "tcp": true, "url": true, "uri": true, "uuid": true,
+}
+
+func readCatalog(r io.Reader) (*Catalog, error) {
+	var c Catalog
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("error parsing catalog: %w", err)
+	}
+	return &c, nil
+}
+
+// validate checks the catalog and fills derived fields.
+func (c *Catalog) validate() error {
+	if !token.IsIdentifier(c.Package) {
+		return fmt.Errorf("invalid package name %q", c.Package)
+	}
+
+	var (
+		codes = make(map[string]bool)
+		names = make(map[string]bool)
+	)
+	for i := range c.Errors {
+		e := &c.Errors[i]
+		if e.Code == "" {
+			return fmt.Errorf("error #%d: empty code", i+1)
+		}
+		if codes[e.Code] {
+			return fmt.Errorf("error %q: duplicate code", e.Code)
+		}
+		codes[e.Code] = true
+
+		if e.Name == "" {
+			e.Name = goName(e.Code, true)
+		}
+		if !token.IsIdentifier(e.Name) || !token.IsExported(e.Name) {
+			return fmt.Errorf("error %q: invalid name %q", e.Code, e.Name)
+		}
+		if names[e.Name] {
+			return fmt.Errorf("error %q: duplicate name %q", e.Code, e.Name)
+		}
+		names[e.Name] = true
+
+		if e.Message == "" {
+			return fmt.Errorf("error %q: empty message", e.Code)
+		}
+		if e.Severity != "" {
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(e.Severity)); err != nil {
+				return fmt.Errorf("error %q: invalid severity %q", e.Code, e.Severity)
+			}
+		}
+		if e.HTTPStatus != 0 && (e.HTTPStatus < 100 || e.HTTPStatus > 599) {
+			return fmt.Errorf("error %q: invalid HTTP status %d", e.Code, e.HTTPStatus)
+		}
+
+		var (
+			params = make(map[string]bool)
+			attrs  []any
+		)
+		for _, p := range e.Params {
+			if p.Name == "" || p.Name == "code" || p.Name == "severity" {
+				return fmt.Errorf("error %q: invalid param name %q", e.Code, p.Name)
+			}
+			if params[goName(p.Name, false)] {
+				return fmt.Errorf("error %q: duplicate param %q", e.Code, p.Name)
+			}
+			params[goName(p.Name, false)] = true
+			if _, ok := paramTypes[p.Type]; !ok {
+				return fmt.Errorf("error %q: unsupported type %q of param %q", e.Code, p.Type, p.Name)
+			}
+			attrs = append(attrs, slog.Any(p.Name, nil))
+		}
+
+		// Params don't have to be used by placeholders, but every placeholder needs a param.
+		var tErr *serror.TemplateError
+		if errors.As(serror.ValidateTemplate(e.Message, attrs...), &tErr) && len(tErr.Missing) > 0 {
+			return fmt.Errorf("error %q: message placeholders without params: %s", e.Code, strings.Join(tErr.Missing, ", "))
+		}
+	}
+
+	return nil
+}
+
+// goName converts snake_case, dot or dash separated name to the Go identifier.
+func goName(name string, exported bool) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for i, w := range words {
+		w = strings.ToLower(w)
+		switch {
+		case i == 0 && !exported:
+		case initialisms[w]:
+			w = strings.ToUpper(w)
+		default:
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		b.WriteString(w)
+	}
+
+	ret := b.String()
+	if ret != "" && unicode.IsDigit(rune(ret[0])) {
+		ret = "_" + ret
+	}
+	if !exported && (token.IsKeyword(ret) || reservedNames[ret]) {
+		ret += "_"
+	}
+	return ret
+}
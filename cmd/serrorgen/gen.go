@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var codeTemplate = template.Must(template.New("code").Funcs(template.FuncMap{
+	"quote":    strconv.Quote,
+	"goName":   goName,
+	"attrFunc": func(typ string) string { return paramTypes[typ] },
+	"level":    levelExpr,
+}).Parse(`// Code generated by serrorgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Catalog.Package}}
+
+import (
+	"log/slog"
+{{- if .NeedsTime}}
+	"time"
+{{- end}}
+
+	"github.com/vovanec/serror"
+)
+
+// Code is the error code, the value of the "code" log attribute. Codes are
+// sentinel errors, errors returned by constructors match their code with errors.Is.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Error codes.
+const (
+{{- range .Catalog.Errors}}
+	Code{{.Name}} Code = {{quote .Code}}
+{{- end}}
+)
+
+// Sentinel errors, errors returned by constructors match them with errors.Is.
+var (
+{{- range .Catalog.Errors}}
+	Err{{.Name}} error = Code{{.Name}}
+{{- end}}
+)
+{{range .Catalog.Errors}}
+// {{.Name}} returns the {{quote .Code}} error: {{.Message}}.
+{{- if .Description}}
+//
+// {{.Description}}
+{{- end}}
+func {{.Name}}({{range .Params}}{{goName .Name false}} {{.Type}}, {{end}}args ...any) error {
+	return serror.NewtDepth(1, {{quote .Message}}, append([]any{
+		slog.Any("code", Code{{.Name}}),
+{{- if .Severity}}
+		slog.String("severity", {{quote .Severity}}),
+{{- end}}
+{{- range .Params}}
+		{{attrFunc .Type}}({{quote .Name}}, {{goName .Name false}}),
+{{- end}}
+	}, args...)...)
+}
+{{end}}
+// Entry describes the catalog error.
+type Entry struct {
+	Code          Code
+	Message       string
+	Severity      slog.Level
+	HTTPStatus    int
+	PublicMessage string
+}
+
+var catalog = map[Code]Entry{
+{{- range .Catalog.Errors}}
+	Code{{.Name}}: {
+		Code:          Code{{.Name}},
+		Message:       {{quote .Message}},
+		Severity:      {{level .Severity}},
+		HTTPStatus:    {{.HTTPStatus}},
+		PublicMessage: {{quote .PublicMessage}},
+	},
+{{- end}}
+}
+
+// Lookup returns the catalog entry of the error code found in the error chain.
+func Lookup(err error) (Entry, bool) {
+	e, ok := catalog[Code(serror.Code(err))]
+	return e, ok
+}
+
+// Entries returns all catalog entries in declaration order.
+func Entries() []Entry {
+	return []Entry{
+{{- range .Catalog.Errors}}
+		catalog[Code{{.Name}}],
+{{- end}}
+	}
+}
+`))
+
+var docTemplate = template.Must(template.New("doc").Funcs(template.FuncMap{
+	"cell":   markdownCell,
+	"params": markdownParams,
+	"level":  func(s string) string { return parseLevel(s).String() },
+}).Parse(`# {{.Catalog.Package}} errors
+
+| Code | Message | Parameters | Severity | HTTP status | Public message |
+|------|---------|------------|----------|-------------|----------------|
+{{- range .Catalog.Errors}}
+| ` + "`{{.Code}}`" + ` | {{cell .Message}} | {{params .Params}} | {{level .Severity}} | {{if .HTTPStatus}}{{.HTTPStatus}}{{end}} | {{cell .PublicMessage}} |
+{{- end}}
+{{range .Catalog.Errors}}{{if .Description}}
+## ` + "`{{.Code}}`" + `
+
+{{.Description}}
+{{end}}{{end}}`))
+
+type templateData struct {
+	Source    string
+	Catalog   *Catalog
+	NeedsTime bool
+}
+
+func newTemplateData(c *Catalog, source string) templateData {
+	d := templateData{
+		Source:  source,
+		Catalog: c,
+	}
+	for _, e := range c.Errors {
+		for _, p := range e.Params {
+			if strings.HasPrefix(p.Type, "time.") {
+				d.NeedsTime = true
+			}
+		}
+	}
+	return d
+}
+
+// generateCode returns the formatted Go source of the catalog package.
+func generateCode(c *Catalog, source string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codeTemplate.Execute(&buf, newTemplateData(c, source)); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// generateDoc returns the markdown table of the catalog errors.
+func generateDoc(c *Catalog, source string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := docTemplate.Execute(&buf, newTemplateData(c, source)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseLevel returns the severity level, errors are of error severity by default.
+func parseLevel(s string) slog.Level {
+	if s == "" {
+		return slog.LevelError
+	}
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(s))
+	return level
+}
+
+// levelExpr returns the Go expression of the severity level.
+func levelExpr(s string) string {
+	level := parseLevel(s)
+	switch level {
+	case slog.LevelDebug:
+		return "slog.LevelDebug"
+	case slog.LevelInfo:
+		return "slog.LevelInfo"
+	case slog.LevelWarn:
+		return "slog.LevelWarn"
+	case slog.LevelError:
+		return "slog.LevelError"
+	}
+	return fmt.Sprintf("slog.Level(%d)", level)
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func markdownParams(params []Param) string {
+	var ret []string
+	for _, p := range params {
+		ret = append(ret, fmt.Sprintf("`%s` %s", p.Name, p.Type))
+	}
+	return strings.Join(ret, ", ")
+}
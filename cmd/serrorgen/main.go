@@ -0,0 +1,114 @@
+// Command serrorgen generates error constructors from the declarative error catalog.
+//
+// Usage:
+//
+//	serrorgen [-out file] [-doc file] [-package name] catalog.json
+//
+// The catalog is the JSON document:
+//
+//	{
+//	  "package": "apperr",
+//	  "errors": [
+//	    {
+//	      "code": "user_not_found",
+//	      "message": "user not found",
+//	      "params": [{"name": "user_id", "type": "int64"}],
+//	      "severity": "warn",
+//	      "http_status": 404,
+//	      "public_message": "The user does not exist."
+//	    }
+//	  ]
+//	}
+//
+// For every error serrorgen emits the Code<Name> constant, the Err<Name> sentinel
+// and the <Name> constructor taking params as typed arguments followed by optional
+// log args, which returns the serror error with "code" and params log attributes.
+// Errors returned by the constructor match the sentinel with errors.Is. The
+// generated Lookup function returns severity, HTTP status and public message of
+// the error. With -doc, the markdown catalog of errors is written as well.
+//
+// Typically, it is run with go:generate:
+//
+//	//go:generate serrorgen -doc ERRORS.md errors.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "serrorgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+
+	var (
+		fs      = flag.NewFlagSet("serrorgen", flag.ContinueOnError)
+		out     = fs.String("out", "", "output Go `file`, <catalog>_gen.go by default")
+		doc     = fs.String("doc", "", "output markdown `file`, not written by default")
+		pkgName = fs.String("package", "", "package `name`, overrides the catalog package and $GOPACKAGE")
+	)
+	fs.SetOutput(stderr)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one catalog file")
+	}
+
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	c, err := readCatalog(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch {
+	case *pkgName != "":
+		c.Package = *pkgName
+	case c.Package == "":
+		c.Package = os.Getenv("GOPACKAGE")
+	}
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	source := filepath.Base(path)
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + "_gen.go"
+	}
+
+	src, err := generateCode(c, source)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		return err
+	}
+
+	if *doc != "" {
+		md, err := generateDoc(c, source)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*doc, md, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		out  = filepath.Join(dir, "errors_gen.go")
+		doc  = filepath.Join(dir, "ERRORS.md")
+		errs bytes.Buffer
+	)
+
+	require.NoError(t, run([]string{"-out", out, "-doc", doc, "testdata/errors.json"}, &errs))
+
+	assertGolden(t, "testdata/errors_gen.go.golden", out)
+	assertGolden(t, "testdata/ERRORS.md.golden", doc)
+}
+
+func assertGolden(t *testing.T, golden, path string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	if *update {
+		require.NoError(t, os.WriteFile(golden, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		catalog string
+		err     string
+	}{
+		{`{"package": "1pkg"}`, `invalid package name "1pkg"`},
+		{`{"package": "p", "errors": [{"message": "m"}]}`, `error #1: empty code`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m"}, {"code": "a", "message": "m"}]}`, `error "a": duplicate code`},
+		{`{"package": "p", "errors": [{"code": "a-b", "message": "m"}, {"code": "a_b", "message": "m"}]}`, `error "a_b": duplicate name "AB"`},
+		{`{"package": "p", "errors": [{"code": "a", "name": "a", "message": "m"}]}`, `error "a": invalid name "a"`},
+		{`{"package": "p", "errors": [{"code": "a"}]}`, `error "a": empty message`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m", "severity": "fatal"}]}`, `error "a": invalid severity "fatal"`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m", "http_status": 1000}]}`, `error "a": invalid HTTP status 1000`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m", "params": [{"name": "code", "type": "string"}]}]}`, `error "a": invalid param name "code"`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m", "params": [{"name": "x", "type": "string"}, {"name": "x", "type": "int"}]}]}`, `error "a": duplicate param "x"`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m", "params": [{"name": "x", "type": "[]byte"}]}]}`, `error "a": unsupported type "[]byte" of param "x"`},
+		{`{"package": "p", "errors": [{"code": "a", "message": "m {x} {y}", "params": [{"name": "x", "type": "int"}]}]}`, `error "a": message placeholders without params: y`},
+	} {
+		c, err := readCatalog(strings.NewReader(tc.catalog))
+		require.NoError(t, err)
+		assert.EqualError(t, c.validate(), tc.err)
+	}
+
+	_, err := readCatalog(strings.NewReader(`{"package": "p", "errs": []}`))
+	assert.Error(t, err)
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "UserNotFound", goName("user_not_found", true))
+	assert.Equal(t, "IDMissing", goName("id.missing", true))
+	assert.Equal(t, "userID", goName("user_id", false))
+	assert.Equal(t, "requestURL", goName("request-url", false))
+	assert.Equal(t, "type_", goName("type", false))
+	assert.Equal(t, "args_", goName("args", false))
+	assert.Equal(t, "serror_", goName("serror", false))
+	assert.Equal(t, "slog_", goName("slog", false))
+	assert.Equal(t, "time_", goName("time", false))
+	assert.Equal(t, "_404", goName("404", true))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type attrCond struct {
+	key    string
+	value  string
+	negate bool
+}
+
+// filter selects records by level, time range and attribute values.
+type filter struct {
+	level    slog.Level
+	hasLevel bool
+	since    time.Time
+	until    time.Time
+	conds    []attrCond
+}
+
+func newFilter(level, since, until string, where []string, now time.Time) (*filter, error) {
+	var (
+		f   filter
+		err error
+	)
+
+	if level != "" {
+		if err = f.level.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid -level value %q", level)
+		}
+		f.hasLevel = true
+	}
+	if f.since, err = parseTime(since, now); err != nil {
+		return nil, fmt.Errorf("invalid -since value %q", since)
+	}
+	if f.until, err = parseTime(until, now); err != nil {
+		return nil, fmt.Errorf("invalid -until value %q", until)
+	}
+
+	for _, w := range where {
+		c, err := parseCond(w)
+		if err != nil {
+			return nil, err
+		}
+		f.conds = append(f.conds, c)
+	}
+
+	return &f, nil
+}
+
+// parseTime parses RFC3339 time or the duration before now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func parseCond(s string) (attrCond, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" || key == "!" {
+		return attrCond{}, fmt.Errorf("invalid -where value %q, expected key=value or key!=value", s)
+	}
+	c := attrCond{key: key, value: value}
+	if strings.HasSuffix(key, "!") {
+		c.key, c.negate = strings.TrimSuffix(key, "!"), true
+	}
+	return c, nil
+}
+
+func (f *filter) match(r record) bool {
+	if !r.isJSON {
+		// Lines which are not JSON are shown only if no filters are set.
+		return !f.hasLevel && f.since.IsZero() && f.until.IsZero() && len(f.conds) == 0
+	}
+
+	if f.hasLevel && (r.noLevel || r.level < f.level) {
+		return false
+	}
+	if !f.since.IsZero() && (r.time.IsZero() || r.time.Before(f.since)) {
+		return false
+	}
+	if !f.until.IsZero() && (r.time.IsZero() || !r.time.Before(f.until)) {
+		return false
+	}
+
+	for _, c := range f.conds {
+		v, ok := r.attrs[c.key]
+		matched := ok && (fmt.Sprint(v) == c.value || formatValue(v) == c.value)
+		if matched == c.negate {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+// Command serrorlog pretty-prints and queries JSON logs produced by slog.JSONHandler,
+// expanding structured errors logged with loghelper.Attr.
+//
+// Usage:
+//
+//	serrorlog [flags] [file ...]
+//
+// Logs are read from the files or from stdin if no files are given. Lines which are
+// not JSON objects are printed as is.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "serrorlog:", err)
+		os.Exit(2)
+	}
+}
+
+type whereFlag []string
+
+func (f *whereFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *whereFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+
+	var (
+		fs    = flag.NewFlagSet("serrorlog", flag.ContinueOnError)
+		where whereFlag
+		level = fs.String("level", "", "minimal level of records to show: debug, info, warn or error")
+		since = fs.String("since", "", "show records at or after the time, RFC3339 or duration before now, e.g. 1h")
+		until = fs.String("until", "", "show records before the time, RFC3339 or duration before now")
+		color = fs.String("color", "auto", "colorize output: auto, always or never")
+		group = fs.Bool("group", false, "group errors by fingerprint and print counts instead of records")
+	)
+	fs.Var(&where, "where", "attribute filter `key=value` or key!=value, nested keys are dot separated, can be repeated")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	f, err := newFilter(*level, *since, *until, where, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var colorize bool
+	switch *color {
+	case "always":
+		colorize = true
+	case "never":
+	case "auto":
+		colorize = isTerminal(stdout)
+	default:
+		return fmt.Errorf("invalid -color value %q", *color)
+	}
+
+	var out output
+	if *group {
+		out = newGrouper(stdout, colorize)
+	} else {
+		out = &printer{w: stdout, color: colorize}
+	}
+
+	if fs.NArg() == 0 {
+		if err := process(stdin, f, out); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = process(file, f, out)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+var errNotFound = errors.New("record not found")
+
+func testLogs(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.TimeValue(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))
+			}
+			return a
+		},
+	}))
+
+	serror.EmitFingerprint(true)
+	t.Cleanup(func() { serror.EmitFingerprint(false) })
+
+	for i := 0; i < 3; i++ {
+		ctx := loghelper.Context(context.Background(), "request.id", fmt.Sprint(i))
+		logger.DebugContext(ctx, "getting user", loghelper.Attr(ctx))
+		err := serror.Wrap(serror.Wrap(errNotFound, "error getting user", "user_id", 42), "error in handler")
+		logger.ErrorContext(ctx, "request failed", loghelper.Attr(ctx), loghelper.Attr(err))
+	}
+
+	return buf.String() + "not a json line\n"
+}
+
+func TestRender(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-color", "never"}, strings.NewReader(testLogs(t)), &out))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "2024-01-02 15:04:05.000 DEBUG getting user  request.id=0", lines[0])
+	assert.Equal(t, "2024-01-02 15:04:05.000 ERROR request failed  request.id=0  user_id=42", lines[1])
+	assert.Equal(t, "    error: error in handler: error getting user: record not found", lines[2])
+	assert.Equal(t, "      ↳ error in handler", lines[3])
+	assert.Equal(t, "      ↳ error getting user", lines[4])
+	assert.Equal(t, "      ↳ record not found", lines[5])
+	assert.Regexp(t, `^    fingerprint: [0-9a-f]{16}$`, lines[6])
+	assert.Equal(t, "    stack:", lines[7])
+	assert.Regexp(t, `^      /.*main_test.go:\d+$`, lines[8])
+	assert.Regexp(t, `^      /.*main_test.go:\d+$`, lines[9])
+	assert.Contains(t, out.String(), "\nnot a json line\n")
+
+	out.Reset()
+	require.NoError(t, run([]string{"-color", "always"}, strings.NewReader(testLogs(t)), &out))
+	assert.Contains(t, out.String(), colorRed+"ERROR"+colorReset)
+}
+
+func TestFilter(t *testing.T) {
+	logs := testLogs(t)
+
+	for name, tc := range map[string]struct {
+		args  []string
+		count int
+	}{
+		"level":       {args: []string{"-level", "error"}, count: 3},
+		"attr":        {args: []string{"-where", "request.id=1"}, count: 2},
+		"not attr":    {args: []string{"-where", "request.id!=1", "-level", "info"}, count: 2},
+		"number attr": {args: []string{"-where", "user_id=42"}, count: 3},
+		"since":       {args: []string{"-since", "2024-01-02T15:04:05Z"}, count: 6},
+		"until":       {args: []string{"-until", "2024-01-02T15:04:05Z"}, count: 0},
+		"duration":    {args: []string{"-since", "1h"}, count: 0},
+	} {
+		args := append([]string{"-color", "never"}, tc.args...)
+
+		var out bytes.Buffer
+		require.NoError(t, run(args, strings.NewReader(logs), &out), name)
+		assert.Equal(t, tc.count, strings.Count(out.String(), "2024-01-02"), name)
+		assert.NotContains(t, out.String(), "not a json line", name)
+	}
+
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"-where", "request.id"}, strings.NewReader(logs), &out))
+	assert.Error(t, run([]string{"-level", "fatal"}, strings.NewReader(logs), &out))
+	assert.Error(t, run([]string{"-since", "yesterday"}, strings.NewReader(logs), &out))
+}
+
+func TestGroup(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-color", "never", "-group"}, strings.NewReader(testLogs(t)), &out))
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "     3 error in handler: error getting user: record not found", lines[0])
+	assert.Regexp(t, `^       fingerprint: [0-9a-f]{16}$`, lines[1])
+	assert.Equal(t, "       seen: 2024-01-02 15:04:05.000 - 2024-01-02 15:04:05.000", lines[2])
+	assert.Regexp(t, `^       origin: /.*main_test.go:\d+$`, lines[3])
+}
+
+func TestGroupWithoutFingerprint(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	for i := 0; i < 3; i++ {
+		err := serror.New(fmt.Sprintf("user %d not found", i), "code", "not_found")
+		logger.Error("request failed", loghelper.Attr(err))
+	}
+	logger.Error("request failed", slog.Group("error", slog.String("msg", "record not found")))
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"-color", "never", "-group"}, &buf, &out))
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "     3 user 0 not found", lines[0])
+	assert.Regexp(t, `^       origin: /.*main_test.go:\d+$`, lines[2])
+	assert.Equal(t, "     1 record not found", lines[3])
+}
+
+func TestStack(t *testing.T) {
+	r := parseRecord(`{"error":{"stack":"/src/my app/main.go:10 /src/my app/db.go:20"}}`)
+	assert.Equal(t, []string{"/src/my app/main.go:10", "/src/my app/db.go:20"}, r.stack())
+	assert.Empty(t, parseRecord(`{"error":{"msg":"error"}}`).stack())
+}
+
+func TestChain(t *testing.T) {
+	r := parseRecord(`{"error":{"msg":"error in handler: sql: no rows in result set","stack":"/app/handler.go:42"}}`)
+	assert.Equal(t, []string{"error in handler", "sql: no rows in result set"}, r.chain())
+	assert.Empty(t, parseRecord(`{"error":{"msg":"sql: no rows in result set"}}`).chain())
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	errKey         = "error"
+	errMsgKey      = "msg"
+	stackKey       = "stack"
+	fingerprintKey = "fingerprint"
+	templateKey    = "template"
+	codeKey        = "code"
+)
+
+// record is the parsed JSON log line.
+type record struct {
+	raw     string
+	time    time.Time
+	level   slog.Level
+	msg     string
+	attrs   map[string]any // flattened attributes, excluding time, level, msg and error
+	err     map[string]any // error group
+	isJSON  bool
+	noLevel bool
+}
+
+func parseRecord(line string) record {
+	r := record{raw: line}
+
+	var m map[string]any
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return r
+	}
+	r.isJSON = true
+
+	if s, ok := m[slog.TimeKey].(string); ok {
+		r.time, _ = time.Parse(time.RFC3339Nano, s)
+	}
+	if s, ok := m[slog.LevelKey].(string); ok {
+		if err := r.level.UnmarshalText([]byte(s)); err != nil {
+			r.noLevel = true
+		}
+	} else {
+		r.noLevel = true
+	}
+	r.msg, _ = m[slog.MessageKey].(string)
+	r.err, _ = m[errKey].(map[string]any)
+
+	delete(m, slog.TimeKey)
+	delete(m, slog.LevelKey)
+	delete(m, slog.MessageKey)
+
+	r.attrs = make(map[string]any)
+	flatten("", m, r.attrs)
+
+	return r
+}
+
+func flatten(prefix string, m map[string]any, out map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// attrKeys returns sorted keys of attributes which are not part of the error group.
+func (r record) attrKeys() []string {
+	var keys []string
+	for k := range r.attrs {
+		if k == errKey || strings.HasPrefix(k, errKey+".") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r record) errorString(key string) string {
+	s, _ := r.err[key].(string)
+	return s
+}
+
+// originEnd matches the line number ending the origin in the logged stack.
+var originEnd = regexp.MustCompile(`:\d+( |$)`)
+
+// stack returns error origins, the stack is logged as the space separated string
+// of file:line origins, so it is split after line numbers as file paths may contain spaces.
+func (r record) stack() []string {
+	s := r.errorString(stackKey)
+
+	var (
+		ret   []string
+		start int
+	)
+	for _, loc := range originEnd.FindAllStringIndex(s, -1) {
+		ret = append(ret, strings.TrimSuffix(s[start:loc[1]], " "))
+		start = loc[1]
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		ret = append(ret, rest)
+	}
+	return ret
+}
+
+// groupKey returns the key errors are grouped by: the error fingerprint, or the error code,
+// message template and origins if the fingerprint is not logged, so messages with variable
+// parts are grouped together. Errors without origins are grouped by the message.
+func (r record) groupKey() string {
+	if fp := r.errorString(fingerprintKey); fp != "" {
+		return fp
+	}
+
+	stack := r.stack()
+	if len(stack) < 1 {
+		return "\x00" + r.errorString(errMsgKey)
+	}
+
+	var code string
+	if v, ok := r.attrs[codeKey]; ok {
+		code = formatValue(v)
+	}
+	parts := []string{code, r.errorString(templateKey)}
+	for _, o := range stack {
+		// Origins are compared by file name and line, like serror.Fingerprint does,
+		// so errors logged by binaries built in different directories are grouped.
+		parts = append(parts, path.Base(o))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// chain splits the error message into messages of wrapped errors at ": " separators. The log
+// only carries the stack of structured errors, so the message is split into at most one message
+// per stack origin and the unstructured root error, e.g. "sql: no rows in result set" wrapped
+// once is split in two. Messages of structured root errors and of wrappers not created with
+// serror may still be split at separators they contain.
+func (r record) chain() []string {
+	n := len(r.stack())
+	if n < 1 {
+		return nil
+	}
+	return strings.SplitN(r.errorString(errMsgKey), ": ", n+1)
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case nil:
+		return "null"
+	case []any, map[string]any:
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(v)
+		return strings.TrimSpace(buf.String())
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type output interface {
+	Write(r record) error
+	Close() error
+}
+
+func process(in io.Reader, f *filter, out output) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		r := parseRecord(line)
+		if !f.match(r) {
+			continue
+		}
+		if err := out.Write(r); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorGreen   = "\x1b[32m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorBold    = "\x1b[1m"
+
+	timeFormat = "2006-01-02 15:04:05.000"
+)
+
+// printer writes records in human-friendly form:
+//
+//	2024-01-02 15:04:05.000 ERROR failed to handle request  request.id=42
+//	    error: error in handleGetUser: sql: no rows in result set
+//	      ↳ error in handleGetUser
+//	      ↳ sql: no rows in result set
+//	    stack:
+//	      /app/handler.go:42
+type printer struct {
+	w     io.Writer
+	color bool
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.color || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p *printer) Write(r record) error {
+	var b strings.Builder
+	p.format(&b, r)
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+func (p *printer) Close() error {
+	return nil
+}
+
+func (p *printer) format(b *strings.Builder, r record) {
+	if !r.isJSON {
+		b.WriteString(r.raw)
+		b.WriteByte('\n')
+		return
+	}
+
+	if !r.time.IsZero() {
+		b.WriteString(p.paint(colorDim, r.time.Format(timeFormat)))
+		b.WriteByte(' ')
+	}
+	if !r.noLevel {
+		b.WriteString(p.paint(levelColor(r.level), fmt.Sprintf("%-5s", r.level)))
+		b.WriteByte(' ')
+	}
+	b.WriteString(p.paint(colorBold, r.msg))
+
+	for _, k := range r.attrKeys() {
+		b.WriteString("  ")
+		b.WriteString(p.paint(colorCyan, k+"="))
+		b.WriteString(formatValue(r.attrs[k]))
+	}
+	b.WriteByte('\n')
+
+	if r.err == nil {
+		return
+	}
+
+	if msg := r.errorString(errMsgKey); msg != "" {
+		fmt.Fprintf(b, "    %s %s\n", p.paint(colorRed, "error:"), msg)
+		if chain := r.chain(); len(chain) > 1 {
+			for _, m := range chain {
+				fmt.Fprintf(b, "      %s %s\n", p.paint(colorDim, "↳"), m)
+			}
+		}
+	}
+	if fp := r.errorString(fingerprintKey); fp != "" {
+		fmt.Fprintf(b, "    %s %s\n", p.paint(colorMagenta, "fingerprint:"), fp)
+	}
+	if stack := r.stack(); len(stack) > 0 {
+		fmt.Fprintf(b, "    %s\n", p.paint(colorMagenta, "stack:"))
+		for _, o := range stack {
+			fmt.Fprintf(b, "      %s\n", p.paint(colorBlue, o))
+		}
+	}
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return colorGreen
+	default:
+		return colorDim
+	}
+}
+
+type errorGroup struct {
+	key   string
+	msg   string
+	stack []string
+	count int
+	first time.Time
+	last  time.Time
+}
+
+// grouper counts records carrying errors by the error fingerprint, or by the
+// error code and origins if the fingerprint is not logged, and writes groups
+// ordered by count on Close.
+type grouper struct {
+	p      *printer
+	w      *bufio.Writer
+	groups map[string]*errorGroup
+}
+
+func newGrouper(w io.Writer, color bool) *grouper {
+	return &grouper{
+		p:      &printer{color: color},
+		w:      bufio.NewWriter(w),
+		groups: make(map[string]*errorGroup),
+	}
+}
+
+func (g *grouper) Write(r record) error {
+	if r.err == nil {
+		return nil
+	}
+
+	key := r.groupKey()
+	eg, ok := g.groups[key]
+	if !ok {
+		eg = &errorGroup{
+			key:   r.errorString(fingerprintKey),
+			msg:   r.errorString(errMsgKey),
+			stack: r.stack(),
+			first: r.time,
+		}
+		g.groups[key] = eg
+	}
+	eg.count++
+	if r.time.After(eg.last) {
+		eg.last = r.time
+	}
+	if !r.time.IsZero() && (eg.first.IsZero() || r.time.Before(eg.first)) {
+		eg.first = r.time
+	}
+
+	return nil
+}
+
+func (g *grouper) Close() error {
+	groups := make([]*errorGroup, 0, len(g.groups))
+	for _, eg := range g.groups {
+		groups = append(groups, eg)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].msg < groups[j].msg
+	})
+
+	p := g.p
+	for _, eg := range groups {
+		fmt.Fprintf(g.w, "%s %s\n", p.paint(colorBold, fmt.Sprintf("%6d", eg.count)), p.paint(colorRed, eg.msg))
+		if eg.key != "" {
+			fmt.Fprintf(g.w, "       %s %s\n", p.paint(colorMagenta, "fingerprint:"), eg.key)
+		}
+		if !eg.first.IsZero() {
+			fmt.Fprintf(g.w, "       %s %s - %s\n", p.paint(colorMagenta, "seen:"),
+				eg.first.Format(timeFormat), eg.last.Format(timeFormat))
+		}
+		if len(eg.stack) > 0 {
+			fmt.Fprintf(g.w, "       %s %s\n", p.paint(colorMagenta, "origin:"), p.paint(colorBlue, eg.stack[0]))
+		}
+	}
+
+	return g.w.Flush()
+}
@@ -0,0 +1,135 @@
+package serror
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/vovanec/serror/internal"
+)
+
+const (
+	ctxKey          = "context"
+	ctxErrKey       = "error"
+	ctxCauseKey     = "cause"
+	ctxDeadlineKey  = "deadline"
+	ctxRemainingKey = "remaining"
+)
+
+var contextKeys atomic.Pointer[map[string]bool]
+
+// SetContextKeys limits log attributes captured from the context by NewCtx and
+// WrapCtx to the given keys. All context log attributes are captured if no keys are given.
+// The limit is process-wide, use WithContextKeys to limit keys for particular calls.
+func SetContextKeys(keys ...string) {
+	contextKeys.Store(keySet(keys))
+}
+
+type contextKeysKey struct{}
+
+// WithContextKeys returns the context limiting log attributes captured from it by NewCtx
+// and WrapCtx to the given keys, overriding SetContextKeys for calls with the context and
+// contexts derived from it. All context log attributes are captured if no keys are given.
+func WithContextKeys(ctx context.Context, keys ...string) context.Context {
+	return context.WithValue(ctx, contextKeysKey{}, keySet(keys))
+}
+
+func keySet(keys []string) *map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	return &m
+}
+
+// allowedKeys returns keys of log attributes captured from the context, or nil if all are.
+func allowedKeys(ctx context.Context) *map[string]bool {
+	if keys, ok := ctx.Value(contextKeysKey{}).(*map[string]bool); ok {
+		return keys
+	}
+	return contextKeys.Load()
+}
+
+// NewCtx is like New, but it also captures log attributes of the context, see
+// SetContextKeys and WithContextKeys, and the state of the context as the "context"
+// group with the context error and cancellation cause if the context is done, the
+// deadline with the time remaining until it if the context has one, and the time
+// elapsed since the context was created by WithCancelCause or WithTimeout.
+func NewCtx(ctx context.Context, message string, args ...any) error {
+	return newError(1, ctx, message, false, append(contextArgs(ctx, nil, true), args...))
+}
+
+// WrapCtx is like Wrap, but it also captures log attributes and the state of the
+// context like NewCtx. Context log attributes already present in the wrapped
+// error chain are not captured again.
+func WrapCtx(ctx context.Context, err error, message string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	var inner map[string]slog.Attr
+	var sErr *sError
+	if As(err, &sErr) {
+		inner = sErr.attrs
+	}
+	return wrapError(1, ctx, err, message, append(contextArgs(ctx, inner, true), args...))
+}
+
+// contextArgs returns log attributes of the context which are allowed and not
+// present in the inner error attributes, and the context state attribute.
+func contextArgs(ctx context.Context, inner map[string]slog.Attr, withCause bool) []any {
+	if ctx == nil {
+		return nil
+	}
+
+	var (
+		ret     []any
+		allowed = allowedKeys(ctx)
+	)
+	for _, a := range internal.LogAttrsFromContext(ctx) {
+		if allowed != nil && !(*allowed)[a.Key] {
+			continue
+		}
+		if _, ok := inner[a.Key]; ok {
+			continue
+		}
+		ret = append(ret, a)
+	}
+
+	if a, ok := contextState(ctx, withCause); ok {
+		ret = append(ret, a)
+	}
+	return ret
+}
+
+// contextState returns the group attribute with the context error, optionally
+// the cancellation cause, the deadline and the elapsed time, if there are any.
+func contextState(ctx context.Context, withCause bool) (slog.Attr, bool) {
+	var attrs []slog.Attr
+
+	if err := ctx.Err(); err != nil {
+		attrs = append(attrs, slog.String(ctxErrKey, err.Error()))
+		if cause := context.Cause(ctx); withCause && cause != nil && cause != err {
+			attrs = append(attrs, slog.Any(ctxCauseKey, cause))
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs,
+			slog.Time(ctxDeadlineKey, deadline),
+			slog.Duration(ctxRemainingKey, time.Until(deadline)),
+		)
+	}
+
+	if elapsed, ok := contextElapsed(ctx); ok {
+		attrs = append(attrs, slog.Duration(ctxElapsedKey, elapsed))
+	}
+
+	if len(attrs) == 0 {
+		return slog.Attr{}, false
+	}
+	return slog.Attr{Key: ctxKey, Value: slog.GroupValue(attrs...)}, true
+}
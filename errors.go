@@ -1,9 +1,11 @@
 package serror
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -15,25 +17,38 @@ const (
 	msgKey       = "msg"
 	errOriginKey = "origin"
 	stackKey     = "stack"
+	fpKey        = "fingerprint"
+	codeKey      = "code"
+	tmplKey      = "template"
 )
 
 type sError struct {
 	err    error
+	msg    string
 	origin Origin
 	attrs  map[string]slog.Attr
 	stack  StackTrace
+	// templated is true if msg is the template the error message was rendered from.
+	templated bool
 }
 
 func (e *sError) LogValue() slog.Value {
 
-	errGroup := slog.Group(errKey, slog.String(msgKey, e.err.Error()))
+	errAttrs := []any{slog.String(msgKey, e.err.Error())}
 	if !e.origin.Empty() {
-		errGroup = slog.Group(errKey,
-			slog.String(msgKey, e.err.Error()),
-			// slog.String(errOriginKey, e.origin.String()),
-			slog.String(stackKey, e.stack.String()),
-		)
+		// errAttrs = append(errAttrs, slog.String(errOriginKey, e.origin.String()))
+		errAttrs = append(errAttrs, slog.String(stackKey, e.stack.String()))
+		if sc := sourceContexts(e.stack); len(sc) > 0 {
+			errAttrs = append(errAttrs, slog.Any(srcKey, sc))
+		}
+	}
+	if e.templated {
+		errAttrs = append(errAttrs, slog.String(tmplKey, e.msg))
+	}
+	if fingerprintEnabled.Load() {
+		errAttrs = append(errAttrs, slog.String(fpKey, Fingerprint(e)))
 	}
+	errGroup := slog.Group(errKey, errAttrs...)
 	attrs := append(internal.MapValues(e.attrs), errGroup)
 
 	sort.Slice(attrs, func(i, j int) bool {
@@ -47,10 +62,14 @@ func (e *sError) Origin() Origin {
 	return e.origin
 }
 
-func (e *sError) StackTrace() []Origin {
+func (e *sError) StackTrace() StackTrace {
 	return e.stack
 }
 
+func (e *sError) Fingerprint() string {
+	return Fingerprint(e)
+}
+
 func (e *sError) StructuredError() string {
 	if len(e.attrs) < 1 {
 		return e.err.Error()
@@ -90,6 +109,9 @@ func (e *sError) Format(s fmt.State, verb rune) {
 	case 'v':
 		if s.Flag('+') || s.Flag('#') {
 			_, _ = fmt.Fprint(s, e.StructuredError())
+			if s.Flag('+') {
+				writeSourceContexts(s, sourceContexts(e.stack))
+			}
 			return
 		}
 		fallthrough
@@ -104,8 +126,58 @@ func (e *sError) Unwrap() error {
 	return e.err
 }
 
+// Is reports whether the target is the value of the "code" log attribute. Codes of
+// error types, like the ones generated by serrorgen, are sentinel errors matched by
+// errors.Is, while string codes never match.
+func (e *sError) Is(target error) bool {
+	code, ok := e.attrs[codeKey]
+	if !ok || code.Value.Kind() != slog.KindAny {
+		return false
+	}
+	v, ok := code.Value.Any().(error)
+	return ok && reflect.TypeOf(v) == reflect.TypeOf(target) && reflect.TypeOf(v).Comparable() && v == target
+}
+
+// Code returns the value of the "code" log attribute attached to the error
+// or any error in its chain, or an empty string if there is none.
+func Code(err error) string {
+	if v, ok := LookupAttr(err, codeKey); ok {
+		return v.String()
+	}
+	return ""
+}
+
+// LookupAttr returns the resolved value of the log attribute with the key attached
+// to the outermost error in the chain having it.
+func LookupAttr(err error, key string) (slog.Value, bool) {
+	for err != nil {
+		var sErr *sError
+		if !As(err, &sErr) {
+			break
+		}
+		if a, ok := sErr.attrs[key]; ok {
+			return a.Value.Resolve(), true
+		}
+		err = sErr.err
+	}
+	return slog.Value{}, false
+}
+
 // New returns an error that formats as the given text with optional log args.
 func New(message string, args ...any) error {
+	return newError(1, nil, message, false, args)
+}
+
+// NewDepth is like New, but the error origin is taken depth stack frames above
+// the caller, so helper functions constructing errors can report their callers.
+// NewDepth(0, ...) is equivalent to New.
+func NewDepth(depth int, message string, args ...any) error {
+	return newError(depth+1, nil, message, false, args)
+}
+
+// newError creates the error, ctx is passed to observers, the first
+// context among args is passed if it is nil.
+func newError(depth int, ctx context.Context, message string, templated bool, args []any) error {
 
 	am := make(map[string]slog.Attr)
 	internal.ParseLogArgs(
@@ -114,40 +186,48 @@ func New(message string, args ...any) error {
 			am[a.Key] = a
 		},
 	)
+	runCreateHooks(ctx, message, nil, args, am)
 
-	if len(am) < 1 {
-		return errors.New(message)
+	text := message
+	if templated {
+		text = renderTemplate(message, am)
 	}
 
-	origin := getOrigin(2)
-	return &sError{
-		err:    errors.New(message),
-		attrs:  am,
-		origin: origin,
-		stack:  []Origin{origin},
+	if len(am) < 1 {
+		return observe(ctx, args, errors.New(text))
 	}
+
+	origin := getOrigin(depth + 2)
+
+	return observe(ctx, args, &sError{
+		err:       errors.New(text),
+		msg:       message,
+		templated: templated,
+		attrs:     am,
+		origin:    origin,
+		stack:     []Origin{origin},
+	})
 }
 
 // Wrap wraps the original error and new returned error will implement an Unwrap interface.
 // This also will add log args to the error if there are any.
 func Wrap(err error, message string, args ...any) error {
+	return wrapError(1, nil, err, message, args)
+}
+
+// wrapError wraps the error, ctx is passed to observers, the first
+// context among args is passed if it is nil.
+func wrapError(depth int, ctx context.Context, err error, message string, args []any) error {
 
 	if err == nil {
 		return nil
 	}
 
-	am := make(map[string]slog.Attr)
-	internal.ParseLogArgs(
-		append([]any{err}, args...),
-		func(a slog.Attr) {
-			if a.Key != errKey {
-				am[a.Key] = a
-			}
-		},
-	)
+	am := errorArgs(err, args)
+	captureStack := runCreateHooks(ctx, message, err, args, am)
 
 	if len(am) < 1 {
-		return fmt.Errorf("%s: %w", message, err)
+		return observe(ctx, args, fmt.Errorf("%s: %w", message, err))
 	}
 
 	var (
@@ -158,18 +238,43 @@ func Wrap(err error, message string, args ...any) error {
 
 	if As(err, &sErr) {
 		origin = sErr.origin
-		stack = append(sErr.stack, getOrigin(2))
+		// The capacity is clipped, so errors wrapping the same error don't share stacks.
+		stack = sErr.stack[:len(sErr.stack):len(sErr.stack)]
+		if captureStack {
+			stack = append(stack, getOrigin(depth+2))
+		}
 	} else {
-		origin = getOrigin(2)
+		origin = getOrigin(depth + 2)
 		stack = []Origin{origin}
 	}
 
-	return &sError{
+	return observe(ctx, args, &sError{
 		err:    fmt.Errorf("%s: %w", message, err),
+		msg:    message,
 		attrs:  am,
 		origin: origin,
 		stack:  stack,
+	})
+}
+
+// errorArgs returns log attributes of the wrapped error and args, except the error
+// group. Attributes of structured errors are copied, so their log value with the
+// stack, source lines and fingerprint is not computed just to be discarded.
+func errorArgs(err error, args []any) map[string]slog.Attr {
+	am := make(map[string]slog.Attr)
+	if sErr, ok := err.(*sError); ok {
+		for k, a := range sErr.attrs {
+			am[k] = a
+		}
+	} else if err != nil {
+		args = append([]any{err}, args...)
 	}
+	internal.ParseLogArgs(args, func(a slog.Attr) {
+		if a.Key != errKey {
+			am[a.Key] = a
+		}
+	})
+	return am
 }
 
 // Unwrap returns the result of recursive calling the Unwrap method on err, if error's
@@ -3,15 +3,24 @@ package serror
 import (
 	"bytes"
 	"context"
+	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
+	"runtime"
 	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/vovanec/serror/loghelper"
+	"github.com/vovanec/serror/internal"
 )
 
 type testErr struct {
@@ -174,127 +183,609 @@ func TestErrorComparison(t *testing.T) {
 	}
 }
 
-type AppVersion struct {
-	Major int
-	Minor int
-	Patch int
+func TestFingerprint(t *testing.T) {
+
+	newErr := func(id int) error {
+		return Wrap(
+			New("user not found", slog.Int("id", id), slog.String("code", "not_found")),
+			"error getting user",
+			slog.Int("attempt", id),
+		)
+	}
+
+	assert.Empty(t, Fingerprint(nil))
+	assert.Equal(t, Fingerprint(newErr(1)), Fingerprint(newErr(2)))
+	assert.NotEqual(t, Fingerprint(newErr(1)), Fingerprint(New("user not found", slog.Int("id", 1))))
+	assert.Equal(t, Fingerprint(io.EOF), Fingerprint(io.EOF))
+	assert.NotEqual(t, Fingerprint(io.EOF), Fingerprint(io.ErrUnexpectedEOF))
+	assert.Equal(t, "not_found", Code(newErr(1)))
+
+	EmitFingerprint(true)
+	defer EmitFingerprint(false)
+
+	err := newErr(1)
+	lv := err.(slog.LogValuer).LogValue()
+	errGroup, ok := groupAttr(lv, "error")
+	if assert.True(t, ok, "error group is missing") {
+		fp, ok := groupAttr(errGroup, "fingerprint")
+		if assert.True(t, ok, "fingerprint is missing") {
+			assert.Equal(t, Fingerprint(err), fp.String())
+		}
+	}
 }
 
-func (v AppVersion) LogValue() slog.Value {
-	return slog.GroupValue(
-		slog.Int("major", v.Major),
-		slog.Int("minor", v.Minor),
-		slog.Int("patch", v.Patch),
-	)
+// groupAttr returns the value of the attribute with the key in the group value.
+func groupAttr(v slog.Value, key string) (slog.Value, bool) {
+	for _, a := range v.Group() {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
 }
 
-type Application struct {
-	Name    string
-	Version AppVersion
-	Build   string
+func TestNewDepth(t *testing.T) {
+
+	newNotFound := func(id int) error {
+		return NewDepth(1, "not found", slog.Int("id", id), slog.String("code", "not_found"))
+	}
+
+	err := newNotFound(1)
+	_, _, line, _ := runtime.Caller(0)
+
+	var sErr *sError
+	assert.True(t, As(err, &sErr))
+	assert.Equal(t, line-1, sErr.Origin().Line)
+	assert.Equal(t, "TestNewDepth", path.Ext(sErr.Origin().Function)[1:])
+
+	newNotFoundt := func(id int) error {
+		return NewtDepth(1, "{id} not found", slog.Int("id", id))
+	}
+
+	err = newNotFoundt(2)
+	_, _, line, _ = runtime.Caller(0)
+
+	assert.EqualError(t, err, "2 not found")
+	assert.True(t, As(err, &sErr))
+	assert.Equal(t, line-1, sErr.Origin().Line)
 }
 
-func (a Application) LogValue() slog.Value {
-	return slog.GroupValue(
-		slog.String("name", a.Name),
-		slog.Any("version", a.Version),
-		slog.Group("build",
-			slog.String("hash", a.Build),
-		),
-	)
+func TestLookupAttr(t *testing.T) {
+
+	inner := New("user not found", slog.String("code", "not_found"), slog.Group("db", slog.String("table", "users")))
+	err := Wrap(fmt.Errorf("error getting user: %w", inner), "error handling request", slog.Int("attempt", 2))
+
+	v, ok := LookupAttr(err, "attempt")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), v.Int64())
+
+	// Attributes of errors wrapped by other errors are found too.
+	v, ok = LookupAttr(err, "code")
+	assert.True(t, ok)
+	assert.Equal(t, "not_found", v.String())
+	assert.Equal(t, "not_found", Code(err))
+
+	_, ok = LookupAttr(err, "missing")
+	assert.False(t, ok)
+	_, ok = LookupAttr(io.EOF, "code")
+	assert.False(t, ok)
 }
 
-type InlineArgs struct {
-	Arg1 string
-	Arg2 string
-	Arg3 string
+type errorCode string
+
+func (c errorCode) Error() string {
+	return string(c)
 }
 
-func (a InlineArgs) LogValue() slog.Value {
-	return slog.GroupValue(
-		slog.String("arg1", a.Arg1),
-		slog.String("arg2", a.Arg2),
-		slog.String("arg3", a.Arg3),
+func TestErrorIsCode(t *testing.T) {
+
+	const (
+		codeNotFound errorCode = "not_found"
+		codeConflict errorCode = "conflict"
 	)
+
+	err := Wrap(New("user not found", slog.Int("id", 1), slog.Any("code", codeNotFound)), "error getting user")
+	assert.True(t, Is(err, codeNotFound))
+	assert.False(t, Is(err, codeConflict))
+	assert.False(t, Is(err, errors.New("not_found")))
+	assert.False(t, Is(New("user not found", slog.Int("id", 1)), codeNotFound))
+	assert.Equal(t, "not_found", Code(err))
+
+	// String codes don't make errors match each other.
+	errNotFound := New("not found", slog.String("code", "not_found"))
+	assert.False(t, Is(New("user not found", slog.String("code", "not_found")), errNotFound))
+	assert.False(t, Is(New("user not found", slog.String("code", "not_found")), errorCode("not_found")))
 }
 
-const expectedLog = `{"time":"","level":"INFO","msg":"application started","application":{"name":"vovan","version":{"major":1,"minor":7,"patch":2},"build":{"hash":"20b8c3f"}},"arg1":"ARG1","arg2":"ARG2","arg3":"ARG3","x":"x"}
-{"time":"","level":"INFO","msg":"logging in doSomethingElse","application":{"name":"vovan","version":{"major":1,"minor":7,"patch":2},"build":{"hash":"20b8c3f"}},"arg1":"ARG1","arg2":"ARG2","arg3":"ARG3"}
-{"time":"","level":"ERROR","msg":"error occurred","a":"a","application":{"name":"vovan","version":{"major":1,"minor":7,"patch":2},"build":{"hash":"20b8c3f"}},"arg1":"ARG1","arg2":"ARG2","arg3":"ARG3","b":"b","c":"c","error":{"msg":"error in doSomething: error in doSomethingElse","stack":""}}
-`
+func TestNewt(t *testing.T) {
 
-func TestErrorLogging(t *testing.T) {
+	newErr := func(id int) error {
+		return Newt("user {user_id} not found in {table} ({{literal}}) for {user.name}, {unknown}",
+			slog.Int("user_id", id),
+			slog.String("table", "users"),
+			slog.Group("user", slog.String("name", "vovan")),
+		)
+	}
 
-	var buf bytes.Buffer
-	logger := slog.New(
-		slog.NewJSONHandler(&buf, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-				if a.Key == "time" || a.Key == "origin" || a.Key == "stack" {
-					a.Value = slog.StringValue("")
+	err := newErr(1)
+	assert.EqualError(t, err, "user 1 not found in users ({literal}) for vovan, {unknown}")
+	assert.Equal(t, Fingerprint(err), Fingerprint(newErr(2)))
+	assert.NotEqual(t, err.Error(), newErr(2).Error())
+
+	var found bool
+	for _, a := range err.(slog.LogValuer).LogValue().Group() {
+		if a.Key == "error" {
+			for _, ga := range a.Value.Group() {
+				if ga.Key == "template" {
+					found = true
+					assert.Equal(t, "user {user_id} not found in {table} ({{literal}}) for {user.name}, {unknown}", ga.Value.String())
 				}
-				return a
-			},
-		}),
+			}
+		}
+	}
+	assert.True(t, found)
+
+	assert.EqualError(t, Newt("plain {message}"), "plain {message}")
+	assert.EqualError(t, Newt("unclosed {brace", "brace", 1), "unclosed {brace")
+}
+
+func TestValidateTemplate(t *testing.T) {
+
+	assert.NoError(t, ValidateTemplate("user {user_id} not found in {table}",
+		slog.Int("user_id", 1), slog.String("table", "users")))
+	assert.NoError(t, ValidateTemplate("user {user.id}", slog.Group("user", slog.Int("id", 1))))
+	assert.NoError(t, ValidateTemplate("{{literal}}"))
+
+	err := ValidateTemplate("user {user_id} not found in {table}", slog.Int("id", 1), "table", "users", "extra", 1)
+
+	var tErr *TemplateError
+	assert.True(t, As(err, &tErr))
+	assert.Equal(t, []string{"user_id"}, tErr.Missing)
+	assert.Equal(t, []string{"extra", "id"}, tErr.Extra)
+	assert.EqualError(t, err, `invalid message template "user {user_id} not found in {table}": `+
+		`missing attributes for placeholders: user_id; attributes without placeholders: extra, id`)
+}
+
+//go:embed testdata/locales/*.json
+var locales embed.FS
+
+func TestLocalize(t *testing.T) {
+
+	assert.NoError(t, LoadMessages(locales, "testdata/locales/*.json"))
+	RegisterMessages("de", map[string]Message{"user_not_found": {Other: "Benutzer {user_id} nicht gefunden."}})
+	fallbacks := []string{"RU"}
+	SetFallback("be", fallbacks...)
+	assert.Equal(t, []string{"RU"}, fallbacks)
+	fallbacks[0] = "de"
+
+	userNotFound := Wrap(
+		New("user not found", MessageID("user_not_found"), slog.Int("user_id", 42)),
+		"error getting user",
 	)
+	assert.Equal(t, "User 42 was not found.", Localize(userNotFound, "en"))
+	assert.Equal(t, "User 42 was not found.", Localize(userNotFound, "fr"))
+	assert.Equal(t, "Benutzer 42 nicht gefunden.", Localize(userNotFound, "de-AT"))
+	assert.Equal(t, "Usuário 42 não encontrado.", Localize(userNotFound, "pt_BR"))
+	assert.Equal(t, "Пользователь 42 не найден.", Localize(userNotFound, "be"))
+
+	internal := Wrap(userNotFound, "error handling request", MessageID("internal"))
+	assert.Equal(t, "Internal error, please try again later.", Localize(internal, "en"))
+	assert.Equal(t, "Internal error, please try again later.", Localize(internal, "ru"))
+
+	itemsNotFound := func(n int) error {
+		return New("items not found", MessageID("items_not_found"), slog.Int("count", n))
+	}
+	for n, want := range map[int]string{0: "No items were found.", 1: "1 item was not found.", 5: "5 items were not found."} {
+		assert.Equal(t, want, Localize(itemsNotFound(n), "en"))
+	}
+	for n, want := range map[int]string{
+		1:  "1 элемент не найден.",
+		3:  "3 элемента не найдены.",
+		11: "11 элементов не найдены.",
+		21: "21 элемент не найден.",
+		25: "25 элементов не найдены.",
+	} {
+		assert.Equal(t, want, Localize(itemsNotFound(n), "ru"))
+	}
 
-	app := Application{
-		Name:  "vovan",
-		Build: "20b8c3f",
-		Version: AppVersion{
-			Major: 1,
-			Minor: 7,
-			Patch: 2,
-		},
+	// Missing plural forms fall back to the next language.
+	RegisterMessages("uk", map[string]Message{"items_not_found": {Count: "count", One: "{count} елемент не знайдено."}})
+	assert.Equal(t, "21 елемент не знайдено.", Localize(itemsNotFound(21), "uk"))
+	assert.Equal(t, "5 items were not found.", Localize(itemsNotFound(5), "uk"))
+	assert.Equal(t, PluralOther, pluralCategory("sr", 5))
+	assert.Equal(t, PluralFew, pluralCategory("hr", 22))
+	assert.Equal(t, PluralOne, pluralCategory("bs", 31))
+
+	assert.Empty(t, Localize(New("no message id", slog.Int("id", 1)), "en"))
+	assert.Empty(t, Localize(New("unknown", MessageID("unknown")), "en"))
+	assert.Empty(t, Localize(io.EOF, "en"))
+
+	assert.Error(t, LoadMessages(locales, "["))
+}
+
+func TestObserver(t *testing.T) {
+
+	type observed struct {
+		ctx context.Context
+		err error
 	}
 
-	inlineArgs := InlineArgs{
-		Arg1: "ARG1",
-		Arg2: "ARG2",
-		Arg3: "ARG3",
+	var got []observed
+	remove := AddObserver(func(ctx context.Context, err error) {
+		got = append(got, observed{ctx, err})
+	})
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	err1 := New("plain")
+	err2 := New("error", ctx, slog.Int("a", 1))
+	err3 := Wrap(err2, "wrapped", slog.Int("b", 2))
+	assert.Nil(t, Wrap(nil, "nil", ctx))
+
+	assert.Equal(t, []observed{{nil, err1}, {ctx, err2}, {nil, err3}}, got)
+
+	remove()
+	remove()
+	_ = New("error", slog.Int("a", 1))
+	assert.Len(t, got, 3)
+}
+
+func TestNewCtx(t *testing.T) {
+
+	ctx := internal.ContextWithLogArgs(context.Background(), slog.String("request_id", "42"), slog.String("user", "vovan"))
+
+	err := NewCtx(ctx, "error")
+	assert.Equal(t, map[string]string{"request_id": "42", "user": "vovan"}, attrStrings(err))
+
+	SetContextKeys("request_id")
+	defer SetContextKeys()
+
+	err = NewCtx(ctx, "error", slog.Int("a", 1))
+	assert.Equal(t, map[string]string{"request_id": "42", "a": "1"}, attrStrings(err))
+
+	// Keys of the context override process-wide keys.
+	err = NewCtx(WithContextKeys(ctx, "user"), "error")
+	assert.Equal(t, map[string]string{"user": "vovan"}, attrStrings(err))
+	err = NewCtx(WithContextKeys(ctx), "error")
+	assert.Equal(t, map[string]string{"request_id": "42", "user": "vovan"}, attrStrings(err))
+
+	cause := New("shutting down", slog.String("reason", "signal"))
+	cctx, cancel := context.WithCancelCause(ctx)
+	cancel(cause)
+
+	err = NewCtx(cctx, "error")
+	var sErr *sError
+	assert.True(t, As(err, &sErr))
+	state := sErr.attrs["context"].Value.Group()
+	assert.Equal(t, "error", state[0].Key)
+	assert.Equal(t, "context canceled", state[0].Value.String())
+	assert.Equal(t, "cause", state[1].Key)
+	assert.Equal(t, cause, state[1].Value.Any())
+
+	dctx, dcancel := context.WithTimeout(ctx, time.Hour)
+	defer dcancel()
+
+	err = NewCtx(dctx, "error")
+	assert.True(t, As(err, &sErr))
+	state = sErr.attrs["context"].Value.Group()
+	assert.Equal(t, "deadline", state[0].Key)
+	assert.Equal(t, "remaining", state[1].Key)
+	assert.Greater(t, state[1].Value.Duration(), 59*time.Minute)
+}
+
+func TestWrapCtx(t *testing.T) {
+
+	ctx := internal.ContextWithLogArgs(context.Background(), slog.String("request_id", "42"))
+	inner := NewCtx(ctx, "inner")
+
+	var observed context.Context
+	remove := AddObserver(func(ctx context.Context, err error) {
+		observed = ctx
+	})
+	defer remove()
+
+	// Request ID from the inner error is kept, and not captured from the context again.
+	err := WrapCtx(internal.ContextWithLogArgs(ctx, slog.String("request_id", "43"), slog.Int("attempt", 2)), inner, "outer")
+	assert.Equal(t, map[string]string{"request_id": "42", "attempt": "2"}, attrStrings(err))
+	assert.NotNil(t, observed)
+	assert.EqualError(t, err, "outer: inner")
+
+	err = WrapCtx(ctx, io.EOF, "error reading")
+	assert.Equal(t, map[string]string{"request_id": "42"}, attrStrings(err))
+	assert.Nil(t, WrapCtx(ctx, nil, "error"))
+}
+
+func attrStrings(err error) map[string]string {
+	var sErr *sError
+	if !As(err, &sErr) {
+		return nil
 	}
+	ret := make(map[string]string)
+	for k, a := range sErr.attrs {
+		ret[k] = a.Value.String()
+	}
+	return ret
+}
 
-	ctx := loghelper.Context(context.Background(), inlineArgs, "application", app)
+func TestWrapStackNotShared(t *testing.T) {
 
-	// loghelper.Attr can be used instead of slog attribute constructors
-	// if we want to extract log attributes from context or errors.
-	logger.Info("application started",
-		loghelper.Attr(
-			ctx,
-			slog.String("x", "x"),
-		),
-	)
+	err := Wrap(Wrap(New("error", slog.Int("a", 1)), "wrapped"), "wrapped")
+	err1 := Wrap(err, "first")
+	err2 := Wrap(err, "second")
 
-	err := doSomething(ctx, logger)
-	assert.Error(t, err)
-	logger.Error("error occurred",
-		loghelper.Attr(ctx, err),
-	)
+	var sErr1, sErr2 *sError
+	assert.True(t, As(err1, &sErr1))
+	assert.True(t, As(err2, &sErr2))
+	assert.NotEqual(t, sErr1.stack[3].Line, sErr2.stack[3].Line)
+}
+
+func TestWrapSkipsLogValue(t *testing.T) {
+
+	var opened int
+	EnableSourceContext(1, WithSourceFS(openFunc(func(name string) (fs.File, error) {
+		opened++
+		return nil, fs.ErrNotExist
+	})))
+	defer EnableSourceContext(0)
 
-	assert.Equal(t, expectedLog, buf.String())
+	err := Wrap(New("error", slog.Int("a", 1)), "wrapped", slog.Int("b", 2))
+	assert.Zero(t, opened)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, attrStrings(err))
+
+	_ = fmt.Sprintf("%+v", err)
+	assert.NotZero(t, opened)
+}
+
+type openFunc func(name string) (fs.File, error)
+
+func (f openFunc) Open(name string) (fs.File, error) { return f(name) }
+
+func TestFromContext(t *testing.T) {
+
+	assert.NoError(t, FromContext(context.Background()))
+
+	ctx := internal.ContextWithLogArgs(context.Background(), slog.String("request_id", "42"))
+
+	cctx, cancel := WithCancelCause(ctx)
+	cancel(New("client disconnected", slog.String("remote_addr", "127.0.0.1")))
+
+	err := FromContext(cctx, slog.Int("attempt", 1))
+	assert.EqualError(t, err, "context canceled: client disconnected")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "127.0.0.1", attrStrings(err)["remote_addr"])
+	assert.Equal(t, "42", attrStrings(err)["request_id"])
+	assert.Equal(t, "1", attrStrings(err)["attempt"])
+
+	var sErr *sError
+	assert.True(t, As(err, &sErr))
+	assert.Len(t, sErr.stack, 2)
+	state := sErr.attrs["context"].Value.Group()
+	assert.Equal(t, "error", state[0].Key)
+	assert.Equal(t, "elapsed", state[1].Key)
+	// The fingerprint depends on the cause origin, not on where FromContext is called.
+	assert.Equal(t, Fingerprint(err), Fingerprint(FromContext(cctx)))
+
+	// Causes which are not structured errors get the origin of the cancel call.
+	cctx, cancel = WithCancelCause(ctx)
+	cancel(nil)
+	_, _, line, _ := runtime.Caller(0)
+
+	err = FromContext(cctx)
+	assert.EqualError(t, err, "context canceled")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, As(err, &sErr))
+	assert.Equal(t, line-1, sErr.stack[0].Line)
+
+	// The cause isn't created when the context is already canceled.
+	assert.Zero(t, testing.AllocsPerRun(10, func() { cancel(nil) }))
+	assert.Equal(t, line-1, context.Cause(cctx).(*sError).stack[0].Line)
+
+	var observed int
+	remove := AddObserver(func(context.Context, error) { observed++ })
+	defer remove()
+
+	tctx, tcancel := WithTimeout(ctx, time.Millisecond, "request timed out", slog.Duration("timeout", time.Millisecond))
+	_, _, line, _ = runtime.Caller(0)
+	defer tcancel()
+	<-tctx.Done()
+	assert.Zero(t, observed)
+	assert.Equal(t, line-1, context.Cause(tctx).(*sError).origin.Line)
+
+	err = FromContext(tctx)
+	assert.EqualError(t, err, "context deadline exceeded: request timed out")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "1ms", attrStrings(err)["timeout"])
+	state = err.(*sError).attrs["context"].Value.Group()
+	assert.Equal(t, []string{"error", "deadline", "remaining", "elapsed"}, []string{state[0].Key, state[1].Key, state[2].Key, state[3].Key})
+
+	// The origin is captured when hooks skip stack capture and the cause has no origin.
+	removeHook := OnCreate(func(info *ErrorInfo) { info.CaptureStack = false })
+	defer removeHook()
+	pctx, pcancel := context.WithCancel(context.Background())
+	pcancel()
+	err = FromContext(pctx)
+	_, _, line, _ = runtime.Caller(0)
+	assert.Equal(t, line-1, err.(*sError).origin.Line)
+	assert.Len(t, err.(*sError).stack, 1)
 }
 
-func doSomethingElse(ctx context.Context, logger *slog.Logger) error {
+func TestOnCreate(t *testing.T) {
 
-	logger.Info("logging in doSomethingElse",
-		loghelper.Attr(ctx))
+	var infos []ErrorInfo
+	removeRecord := OnCreate(func(info *ErrorInfo) {
+		infos = append(infos, *info)
+	})
+	defer removeRecord()
 
-	return New("error in doSomethingElse",
-		slog.String("a", "a"),
-	)
+	hostHook := func(info *ErrorInfo) {
+		info.AddAttrs(slog.String("host", "localhost"), slog.String("code", "default"))
+		if code, ok := info.Attr("code"); ok && code.String() == "no_stack" {
+			info.CaptureStack = false
+		}
+	}
+	removeHost := OnCreate(hostHook)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	err := New("plain", ctx)
+	assert.Equal(t, map[string]string{"host": "localhost", "code": "default"}, attrStrings(err))
+	assert.Equal(t, ctx, infos[0].Context)
+	assert.Equal(t, "plain", infos[0].Message)
+	assert.Nil(t, infos[0].Wrapped)
+
+	err = Wrap(err, "wrapped", slog.String("code", "explicit"))
+	assert.Equal(t, "explicit", Code(err))
+	assert.Equal(t, "plain", infos[1].Wrapped.Error())
+	assert.Len(t, err.(*sError).stack, 2)
+
+	sampled := Wrap(err, "no stack", slog.String("code", "no_stack"))
+	assert.Len(t, sampled.(*sError).stack, 2)
+
+	removeHost()
+	unsampled := Wrap(err, "no stack", slog.String("code", "no_stack"))
+	assert.Len(t, unsampled.(*sError).stack, 3)
+	assert.Equal(t, Fingerprint(unsampled), Fingerprint(sampled))
+	removeHost = OnCreate(hostHook)
+
+	// The origin of new errors is always captured.
+	err = New("no stack", slog.String("code", "no_stack"))
+	assert.False(t, err.(*sError).Origin().Empty())
+	assert.Len(t, err.(*sError).stack, 1)
+
+	removeHost()
+	removeHost()
+	assert.EqualError(t, New("plain"), "plain")
+	assert.IsType(t, errors.New(""), New("plain"))
+	assert.Len(t, infos, 7)
 }
 
-func doSomething(ctx context.Context, logger *slog.Logger) error {
-	if err := doSomethingElse(ctx, logger); err != nil {
-		return Wrap(err, "error in doSomething",
-			loghelper.Attr(
-				// usually one doesn't have to attach the context since caller
-				// already has it, but it can be done.
-				ctx,
-				slog.String("b", "b"),
-				slog.String("c", "c"),
-			),
-		)
+func sourceErr() error {
+	return New("source error", slog.Int("id", 1)) // source marker
+}
+
+func TestSourceContext(t *testing.T) {
+	defer EnableSourceContext(0)
+
+	err := Wrap(sourceErr(), "wrapped", slog.Int("attempt", 1))
+	assert.NotContains(t, fmt.Sprintf("%+v", err), "source marker")
+
+	EnableSourceContext(1)
+	out := fmt.Sprintf("%+v", err)
+	assert.Regexp(t, `\n> +\d+  \treturn New\("source error", slog.Int\("id", 1\)\) // source marker\n`, out)
+	assert.Regexp(t, `\n> +\d+  \terr := Wrap\(sourceErr\(\), "wrapped", slog.Int\("attempt", 1\)\)\n`, out)
+	assert.Equal(t, "wrapped: source error", fmt.Sprintf("%v", err))
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Error("error", "error", err)
+	var rec struct {
+		Error struct {
+			Error struct {
+				Source []SourceContext `json:"source"`
+			} `json:"error"`
+		} `json:"error"`
 	}
-	return nil
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+
+	origin := err.(*sError).origin
+	sc := rec.Error.Error.Source
+	assert.Len(t, sc, 2)
+	assert.Equal(t, origin.File, sc[0].File)
+	assert.Equal(t, origin.Line, sc[0].Line)
+	assert.Equal(t, []int{origin.Line - 1, origin.Line, origin.Line + 1}, []int{sc[0].Lines[0].Line, sc[0].Lines[1].Line, sc[0].Lines[2].Line})
+	assert.Contains(t, sc[0].Lines[1].Text, "source marker")
+
+	var lines []string
+	for i := 1; i <= origin.Line+5; i++ {
+		lines = append(lines, fmt.Sprintf("embedded line %d", i))
+	}
+	EnableSourceContext(2, WithSourceFS(fstest.MapFS{
+		"errors_test.go": {Data: []byte(strings.Join(lines, "\n"))},
+	}))
+	out = fmt.Sprintf("%+v", err)
+	assert.Contains(t, out, fmt.Sprintf("\n> %5d  embedded line %d\n", origin.Line, origin.Line))
+	assert.Contains(t, out, fmt.Sprintf("\n  %5d  embedded line %d\n", origin.Line-2, origin.Line-2))
+	assert.NotContains(t, out, "source marker")
+
+	EnableSourceContext(1, WithSourceCacheSize(100))
+	assert.NotContains(t, fmt.Sprintf("%+v", err), "\n>")
+
+	// Files of other packages are not matched by their names only.
+	EnableSourceContext(1, WithSourceFS(fstest.MapFS{
+		"sub/a.go": {Data: []byte("sub a")},
+		"a.go":     {Data: []byte("root a")},
+	}))
+	r := sourceCtx.Load()
+	assert.Equal(t, []string{"sub a"}, r.file(Origin{File: "/src/mod/sub/a.go", Function: "example.com/mod/sub.F"}))
+	assert.Equal(t, []string{"root a"}, r.file(Origin{File: "/src/mod/a.go", Function: "github.com/vovanec/serror.F"}))
+	assert.Nil(t, r.file(Origin{File: "/src/mod/other/a.go", Function: "example.com/mod/other.F"}))
+
+	EnableSourceContext(1, WithSourceCacheSize(100), WithSourceFS(fstest.MapFS{
+		"x/a.go": {Data: []byte(strings.Repeat("a", 60))},
+		"x/b.go": {Data: []byte(strings.Repeat("b", 60))},
+	}))
+	r = sourceCtx.Load()
+	assert.Equal(t, []string{strings.Repeat("a", 60)}, r.file(Origin{File: "x/a.go"}))
+	assert.Equal(t, []string{strings.Repeat("b", 60)}, r.file(Origin{File: "x/b.go"}))
+	assert.Nil(t, r.file(Origin{File: "x/c.go"}))
+	assert.Len(t, r.cache, 2)
+	assert.Equal(t, 72, r.size)
+}
+
+func BenchmarkNew(b *testing.B) {
+
+	b.Run("no attrs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = New("error")
+		}
+	})
+
+	b.Run("attrs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = New("error", slog.Int("a", i))
+		}
+	})
+
+	b.Run("hook", func(b *testing.B) {
+		remove := OnCreate(func(info *ErrorInfo) {
+			info.AddAttrs(slog.String("host", "localhost"))
+		})
+		defer remove()
+
+		for i := 0; i < b.N; i++ {
+			_ = New("error", slog.Int("a", i))
+		}
+	})
+}
+
+func BenchmarkWrap(b *testing.B) {
+
+	err := New("error", slog.Int("a", 1))
+
+	b.Run("no hooks", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Wrap(err, "wrapped", slog.Int("b", i))
+		}
+	})
+
+	b.Run("hook", func(b *testing.B) {
+		remove := OnCreate(func(info *ErrorInfo) {})
+		defer remove()
+
+		for i := 0; i < b.N; i++ {
+			_ = Wrap(err, "wrapped", slog.Int("b", i))
+		}
+	})
+
+	b.Run("hook without stack", func(b *testing.B) {
+		remove := OnCreate(func(info *ErrorInfo) {
+			info.CaptureStack = false
+		})
+		defer remove()
+
+		for i := 0; i < b.N; i++ {
+			_ = Wrap(err, "wrapped", slog.Int("b", i))
+		}
+	})
 }
@@ -0,0 +1,4 @@
+// Package apperr is the example error catalog generated by serrorgen from errors.json.
+package apperr
+
+//go:generate go run github.com/vovanec/serror/cmd/serrorgen -doc ERRORS.md errors.json
@@ -0,0 +1,87 @@
+// Code generated by serrorgen from errors.json. DO NOT EDIT.
+
+package apperr
+
+import (
+	"log/slog"
+
+	"github.com/vovanec/serror"
+)
+
+// Code is the error code, the value of the "code" log attribute. Codes are
+// sentinel errors, errors returned by constructors match their code with errors.Is.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Error codes.
+const (
+	CodeUserNotFound  Code = "user_not_found"
+	CodeInternalError Code = "internal"
+)
+
+// Sentinel errors, errors returned by constructors match them with errors.Is.
+var (
+	ErrUserNotFound  error = CodeUserNotFound
+	ErrInternalError error = CodeInternalError
+)
+
+// UserNotFound returns the "user_not_found" error: user {user_id} not found.
+//
+// The user with the given ID is not found in the database.
+func UserNotFound(userID int64, args ...any) error {
+	return serror.NewtDepth(1, "user {user_id} not found", append([]any{
+		slog.Any("code", CodeUserNotFound),
+		slog.String("severity", "warn"),
+		slog.Int64("user_id", userID),
+	}, args...)...)
+}
+
+// InternalError returns the "internal" error: internal error.
+func InternalError(args ...any) error {
+	return serror.NewtDepth(1, "internal error", append([]any{
+		slog.Any("code", CodeInternalError),
+	}, args...)...)
+}
+
+// Entry describes the catalog error.
+type Entry struct {
+	Code          Code
+	Message       string
+	Severity      slog.Level
+	HTTPStatus    int
+	PublicMessage string
+}
+
+var catalog = map[Code]Entry{
+	CodeUserNotFound: {
+		Code:          CodeUserNotFound,
+		Message:       "user {user_id} not found",
+		Severity:      slog.LevelWarn,
+		HTTPStatus:    404,
+		PublicMessage: "The user does not exist.",
+	},
+	CodeInternalError: {
+		Code:          CodeInternalError,
+		Message:       "internal error",
+		Severity:      slog.LevelError,
+		HTTPStatus:    500,
+		PublicMessage: "Internal server error.",
+	},
+}
+
+// Lookup returns the catalog entry of the error code found in the error chain.
+func Lookup(err error) (Entry, bool) {
+	e, ok := catalog[Code(serror.Code(err))]
+	return e, ok
+}
+
+// Entries returns all catalog entries in declaration order.
+func Entries() []Entry {
+	return []Entry{
+		catalog[CodeUserNotFound],
+		catalog[CodeInternalError],
+	}
+}
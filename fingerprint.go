@@ -0,0 +1,90 @@
+package serror
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"path"
+	"strconv"
+	"sync/atomic"
+)
+
+var fingerprintEnabled atomic.Bool
+
+// EmitFingerprint enables or disables adding the error fingerprint
+// to the error log value as error.fingerprint attribute.
+func EmitFingerprint(enabled bool) {
+	fingerprintEnabled.Store(enabled)
+}
+
+// Fingerprint returns a stable hash of the error suitable for grouping and
+// deduplication of identical failures. The hash is computed from the error code,
+// the error origin (function, file name and line) and the error messages passed
+// to New and Wrap. Log attribute values are ignored, so the same failure reported
+// with different data produces the same fingerprint.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	writeField(h, Code(err))
+
+	// Only the innermost origin is used, Wrap frames may be skipped by hooks
+	// and would make the fingerprint of the same failure differ.
+	var sErr *sError
+	if As(err, &sErr) {
+		o := sErr.origin
+		writeField(h, o.Function)
+		writeField(h, path.Base(o.File)+":"+strconv.Itoa(o.Line))
+	}
+	fingerprintChain(h, err)
+
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+func fingerprintChain(h hash.Hash, err error) {
+	for err != nil {
+		switch x := err.(type) {
+		case *sError:
+			writeField(h, x.msg)
+			if m, ok := x.err.(interface{ Unwrap() []error }); ok {
+				for _, e := range m.Unwrap() {
+					fingerprintChain(h, e)
+				}
+				return
+			}
+			// The wrapped error message embeds the inner error text,
+			// skip straight to the error that was wrapped.
+			err = errors.Unwrap(x.err)
+			if err == nil {
+				return
+			}
+			continue
+		case interface{ Unwrap() []error }:
+			for _, e := range x.Unwrap() {
+				fingerprintChain(h, e)
+			}
+			return
+		}
+
+		if next := errors.Unwrap(err); next != nil {
+			// Messages of intermediate wrappers usually contain variable data,
+			// only their types are taken into account.
+			writeField(h, fmt.Sprintf("%T", err))
+			err = next
+			continue
+		}
+
+		writeField(h, fmt.Sprintf("%T", err))
+		writeField(h, err.Error())
+		return
+	}
+}
+
+func writeField(h hash.Hash, s string) {
+	_, _ = h.Write([]byte(s))
+	_, _ = h.Write([]byte{0})
+}
@@ -0,0 +1,120 @@
+package serror
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vovanec/serror/internal"
+)
+
+// ErrorInfo describes the error being created by New, Wrap or their variants,
+// hooks registered with OnCreate can modify it.
+type ErrorInfo struct {
+	// Context is the context passed to NewCtx, WrapCtx or among log args, or nil.
+	Context context.Context
+	// Message is the error message, or the template for Newt.
+	Message string
+	// Wrapped is the error being wrapped, or nil for New.
+	Wrapped error
+	// CaptureStack reports whether Wrap adds its location to the stack of the wrapped
+	// structured error, which is the most expensive part of wrapping. Hooks can set it
+	// to false to skip it. The error origin is always captured, since it identifies
+	// the failure, e.g. in fingerprints.
+	CaptureStack bool
+
+	attrs map[string]slog.Attr
+}
+
+// Attr returns the value of the log attribute of the error.
+func (i *ErrorInfo) Attr(key string) (slog.Value, bool) {
+	a, ok := i.attrs[key]
+	if !ok {
+		return slog.Value{}, false
+	}
+	return a.Value.Resolve(), true
+}
+
+// AddAttrs adds log attributes parsed from args to the error, unless
+// the error already has attributes with the same keys.
+func (i *ErrorInfo) AddAttrs(args ...any) {
+	internal.ParseLogArgs(args, func(a slog.Attr) {
+		if _, ok := i.attrs[a.Key]; !ok && a.Key != errKey {
+			i.attrs[a.Key] = a
+		}
+	})
+}
+
+// OnCreate registers the hook called for every error created by New, Wrap and their
+// variants before the error is constructed. Hooks can add log attributes and skip
+// capturing stack frames of wrapping errors, they are called synchronously in the
+// order they were registered. The returned function removes the hook.
+func OnCreate(hook func(info *ErrorInfo)) (remove func()) {
+	return createHooks.add(hook)
+}
+
+var createHooks hookList[func(info *ErrorInfo)]
+
+// runCreateHooks calls hooks and returns whether the Wrap stack frame should be captured.
+func runCreateHooks(ctx context.Context, message string, wrapped error, args []any, am map[string]slog.Attr) bool {
+	hooks := createHooks.load()
+	if len(hooks) == 0 {
+		return true
+	}
+
+	if ctx == nil {
+		ctx = contextOf(args)
+	}
+	info := ErrorInfo{
+		Context:      ctx,
+		Message:      message,
+		Wrapped:      wrapped,
+		CaptureStack: true,
+		attrs:        am,
+	}
+	for _, h := range hooks {
+		(*h)(&info)
+	}
+	return info.CaptureStack
+}
+
+// hookList is the list of hooks which can be read without locking.
+type hookList[T any] struct {
+	mu sync.Mutex
+	// list is replaced on every change.
+	list atomic.Pointer[[]*T]
+}
+
+func (l *hookList[T]) load() []*T {
+	if p := l.list.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (l *hookList[T]) add(h T) (remove func()) {
+	p := &h
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := append(append([]*T(nil), l.load()...), p)
+	l.list.Store(&list)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			var list []*T
+			for _, lp := range l.load() {
+				if lp != p {
+					list = append(list, lp)
+				}
+			}
+			l.list.Store(&list)
+		})
+	}
+}
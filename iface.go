@@ -18,3 +18,9 @@ type StructuredError interface {
 type StackTracer interface {
 	StackTrace() StackTrace
 }
+
+// Fingerprinter is the interface that provides the Fingerprint() method,
+// which returns a stable hash identifying the failure.
+type Fingerprinter interface {
+	Fingerprint() string
+}
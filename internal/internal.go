@@ -15,7 +15,13 @@ var logAttrCtxKey logAttrCtxKeyType
 
 func ContextWithLogArgs(ctx context.Context, args ...any) context.Context {
 
-	am := logAttrsFromContext(ctx)
+	// Parent context attributes are copied, so attributes added
+	// to the derived context are not visible in the parent one.
+	parent := logAttrsFromContext(ctx)
+	am := make(map[string]slog.Attr, len(parent)+len(args))
+	for k, a := range parent {
+		am[k] = a
+	}
 	ParseLogArgs(args, func(a slog.Attr) {
 		am[a.Key] = a
 	})
@@ -27,6 +33,20 @@ func ContextWithLogArgs(ctx context.Context, args ...any) context.Context {
 	)
 }
 
+// LogAttrFromContext returns the log attribute with the given key attached to the context.
+func LogAttrFromContext(ctx context.Context, key string) (slog.Attr, bool) {
+	if am, ok := ctx.Value(logAttrCtxKey).(map[string]slog.Attr); ok {
+		a, ok := am[key]
+		return a, ok
+	}
+	return slog.Attr{}, false
+}
+
+// LogAttrsFromContext returns log attributes attached to the context.
+func LogAttrsFromContext(ctx context.Context) []slog.Attr {
+	return MapValues(logAttrsFromContext(ctx))
+}
+
 func ParseLogArgs(args []any, f AttrFunc) {
 
 	am := make(map[string]slog.Attr)
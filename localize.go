@@ -0,0 +1,376 @@
+package serror
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const msgIDKey = "message_id"
+
+// PluralCategory is the CLDR plural category of the count.
+type PluralCategory string
+
+const (
+	PluralZero  PluralCategory = "zero"
+	PluralOne   PluralCategory = "one"
+	PluralTwo   PluralCategory = "two"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+// PluralRule returns the plural category of the count in the language.
+type PluralRule func(n int64) PluralCategory
+
+// Message is the translation of the public error message. Placeholders like
+// {user_id} are replaced with error log attributes the same way as in Newt.
+// If Count is set, the form is chosen by the plural category of the log attribute
+// with that key, a message in JSON can be either a string or an object:
+//
+//	{
+//	  "user_not_found": "User {user_id} was not found.",
+//	  "items_not_found": {
+//	    "count": "count",
+//	    "zero": "No items were found.",
+//	    "one": "{count} item was not found.",
+//	    "other": "{count} items were not found."
+//	  }
+//	}
+type Message struct {
+	// Count is the key of the log attribute selecting the plural form.
+	Count string `json:"count,omitempty"`
+	// Zero is used for the zero count regardless of the language plural rule, if set.
+	Zero  string `json:"zero,omitempty"`
+	One   string `json:"one,omitempty"`
+	Two   string `json:"two,omitempty"`
+	Few   string `json:"few,omitempty"`
+	Many  string `json:"many,omitempty"`
+	Other string `json:"other,omitempty"`
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = Message{Other: s}
+		return nil
+	}
+	type message Message
+	return json.Unmarshal(data, (*message)(m))
+}
+
+func (m Message) form(c PluralCategory) string {
+	var ret string
+	switch c {
+	case PluralZero:
+		ret = m.Zero
+	case PluralOne:
+		ret = m.One
+	case PluralTwo:
+		ret = m.Two
+	case PluralFew:
+		ret = m.Few
+	case PluralMany:
+		ret = m.Many
+	}
+	if ret == "" {
+		ret = m.Other
+	}
+	return ret
+}
+
+// MessageID returns the log attribute with the ID of the public error message,
+// which is rendered in the user's language by Localize.
+func MessageID(id string) slog.Attr {
+	return slog.String(msgIDKey, id)
+}
+
+var translations = struct {
+	sync.RWMutex
+	messages    map[string]map[string]Message
+	rules       map[string]PluralRule
+	fallbacks   map[string][]string
+	defaultLang string
+}{
+	messages:    make(map[string]map[string]Message),
+	rules:       defaultPluralRules(),
+	fallbacks:   make(map[string][]string),
+	defaultLang: "en",
+}
+
+// RegisterMessages adds translations of public error messages by message ID for the language.
+func RegisterMessages(lang string, messages map[string]Message) {
+	lang = normalizeLang(lang)
+
+	translations.Lock()
+	defer translations.Unlock()
+
+	m, ok := translations.messages[lang]
+	if !ok {
+		m = make(map[string]Message, len(messages))
+		translations.messages[lang] = m
+	}
+	for id, msg := range messages {
+		m[id] = msg
+	}
+}
+
+// LoadMessages registers translations from JSON files of the file system matching the
+// pattern, usually the embed.FS. The language is the file name without extension:
+//
+//	//go:embed locales/*.json
+//	var locales embed.FS
+//
+//	err := serror.LoadMessages(locales, "locales/*.json")
+func LoadMessages(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return Wrap(err, "error loading messages", slog.String("pattern", pattern))
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return Wrap(err, "error loading messages", slog.String("file", name))
+		}
+		var messages map[string]Message
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return Wrap(err, "error parsing messages", slog.String("file", name))
+		}
+		RegisterMessages(strings.TrimSuffix(path.Base(name), path.Ext(name)), messages)
+	}
+
+	return nil
+}
+
+// RegisterPluralRule sets the plural rule of the language. Rules for common
+// languages are registered by default, other languages use the English one.
+func RegisterPluralRule(lang string, rule PluralRule) {
+	translations.Lock()
+	defer translations.Unlock()
+	translations.rules[normalizeLang(lang)] = rule
+}
+
+// SetFallback sets languages tried in order when the message is not translated to
+// the language. Without explicit fallbacks, the language without region subtag, like
+// "pt" for "pt-BR", is tried. The default language is always tried last.
+func SetFallback(lang string, fallbacks ...string) {
+	// The caller's slice is neither modified nor retained.
+	langs := make([]string, len(fallbacks))
+	for i, l := range fallbacks {
+		langs[i] = normalizeLang(l)
+	}
+
+	translations.Lock()
+	defer translations.Unlock()
+	translations.fallbacks[normalizeLang(lang)] = langs
+}
+
+// SetDefaultLanguage sets the last language of fallback chains, "en" by default.
+func SetDefaultLanguage(lang string) {
+	translations.Lock()
+	defer translations.Unlock()
+	translations.defaultLang = normalizeLang(lang)
+}
+
+// Localize renders the public message of the outermost error in the chain carrying
+// the message ID attribute, see MessageID, in the language or its fallbacks.
+// Empty translations and plural forms are treated as missing. It returns an empty
+// string if there is no message ID or translation.
+func Localize(err error, lang string) string {
+	var sErr *sError
+	if !As(err, &sErr) {
+		return ""
+	}
+	// Wrapping errors inherit log attributes of wrapped ones, so the outermost
+	// structured error carries the outermost message ID.
+	id, ok := sErr.attrs[msgIDKey]
+	if !ok {
+		return ""
+	}
+
+	translations.RLock()
+	defer translations.RUnlock()
+
+	for _, l := range fallbackChain(normalizeLang(lang)) {
+		msg, ok := translations.messages[l][id.Value.Resolve().String()]
+		if !ok {
+			continue
+		}
+
+		category := PluralOther
+		if msg.Count != "" {
+			if n, ok := attrCount(sErr.attrs, msg.Count); ok {
+				category = pluralCategory(l, n)
+				if n == 0 && msg.Zero != "" {
+					category = PluralZero
+				}
+			}
+		}
+		if form := msg.form(category); form != "" {
+			return renderTemplate(form, sErr.attrs)
+		}
+	}
+
+	return ""
+}
+
+// fallbackChain returns languages to look up messages in, translations must be locked.
+func fallbackChain(lang string) []string {
+	var (
+		ret  []string
+		seen = make(map[string]bool)
+	)
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			ret = append(ret, l)
+		}
+	}
+
+	add(lang)
+	if fallbacks, ok := translations.fallbacks[lang]; ok {
+		for _, l := range fallbacks {
+			add(l)
+		}
+	} else {
+		for l := lang; strings.Contains(l, "-"); {
+			l = l[:strings.LastIndexByte(l, '-')]
+			add(l)
+		}
+	}
+	add(translations.defaultLang)
+
+	return ret
+}
+
+func pluralCategory(lang string, n int64) PluralCategory {
+	for l := lang; ; l = l[:strings.LastIndexByte(l, '-')] {
+		if rule, ok := translations.rules[l]; ok {
+			return rule(n)
+		}
+		if !strings.Contains(l, "-") {
+			break
+		}
+	}
+	return pluralOneOther(n)
+}
+
+func attrCount(am map[string]slog.Attr, key string) (int64, bool) {
+	a, ok := lookupAttr(am, key)
+	if !ok {
+		return 0, false
+	}
+	switch a.Value.Kind() {
+	case slog.KindInt64:
+		return a.Value.Int64(), true
+	case slog.KindUint64:
+		return int64(a.Value.Uint64()), true
+	case slog.KindFloat64:
+		return int64(a.Value.Float64()), true
+	case slog.KindString:
+		n, err := strconv.ParseInt(a.Value.String(), 10, 64)
+		return n, err == nil
+	case slog.KindAny:
+		n, err := strconv.ParseInt(fmt.Sprint(a.Value.Any()), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// normalizeLang converts language tags like "pt_BR" to "pt-br".
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
+
+func defaultPluralRules() map[string]PluralRule {
+	rules := make(map[string]PluralRule)
+	for _, l := range []string{"en", "de", "nl", "sv", "da", "no", "nb", "fi", "it", "es", "pt", "el", "hu", "tr", "bg"} {
+		rules[l] = pluralOneOther
+	}
+	for _, l := range []string{"ja", "zh", "ko", "vi", "th", "id", "ms"} {
+		rules[l] = pluralOther
+	}
+	for _, l := range []string{"ru", "uk", "be"} {
+		rules[l] = pluralEastSlavic
+	}
+	for _, l := range []string{"sr", "hr", "bs"} {
+		rules[l] = pluralSerboCroatian
+	}
+	rules["fr"] = pluralFrench
+	rules["pt-br"] = pluralFrench
+	rules["pl"] = pluralPolish
+	rules["cs"] = pluralCzech
+	rules["sk"] = pluralCzech
+	return rules
+}
+
+func pluralOther(int64) PluralCategory {
+	return PluralOther
+}
+
+func pluralOneOther(n int64) PluralCategory {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralFrench(n int64) PluralCategory {
+	if n == 0 || n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralEastSlavic(n int64) PluralCategory {
+	n = abs(n)
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return PluralOne
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+// pluralSerboCroatian is like pluralEastSlavic, but without the "many" category.
+func pluralSerboCroatian(n int64) PluralCategory {
+	if c := pluralEastSlavic(n); c != PluralMany {
+		return c
+	}
+	return PluralOther
+}
+
+func pluralPolish(n int64) PluralCategory {
+	n = abs(n)
+	switch {
+	case n == 1:
+		return PluralOne
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return PluralFew
+	}
+	return PluralMany
+}
+
+func pluralCzech(n int64) PluralCategory {
+	switch {
+	case n == 1:
+		return PluralOne
+	case n >= 2 && n <= 4:
+		return PluralFew
+	}
+	return PluralOther
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
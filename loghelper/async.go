@@ -0,0 +1,266 @@
+package loghelper
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy defines what the asynchronous handler does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops the record.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock blocks the caller until there is space in the queue.
+	OverflowBlock
+)
+
+type AsyncOption func(c *asyncConfig)
+
+// WithQueueSize sets the number of records the queue can hold, 1024 by default.
+func WithQueueSize(size int) AsyncOption {
+	return func(c *asyncConfig) {
+		c.queueSize = size
+	}
+}
+
+// WithOverflowPolicy sets what to do with records when the queue is full, OverflowDrop by default.
+func WithOverflowPolicy(p OverflowPolicy) AsyncOption {
+	return func(c *asyncConfig) {
+		c.policy = p
+	}
+}
+
+// WithAsyncErrorLevel sets the level starting from which records are never dropped.
+// Records carrying errors are never dropped regardless of their level. Default is slog.LevelError.
+func WithAsyncErrorLevel(level slog.Level) AsyncOption {
+	return func(c *asyncConfig) {
+		c.errorLevel = level
+	}
+}
+
+// AsyncHandler is the slog.Handler middleware which passes records to the next handler
+// in a background goroutine through a bounded queue. Records at the error level or
+// carrying errors are always delivered, blocking the caller if the queue is full.
+// Close must be called to deliver queued records on shutdown.
+type AsyncHandler struct {
+	next  slog.Handler
+	state *asyncState
+}
+
+// NewAsyncHandler returns the asynchronous handler and starts its background goroutine.
+func NewAsyncHandler(next slog.Handler, opts ...AsyncOption) *AsyncHandler {
+
+	conf := asyncConfig{
+		queueSize:  1024,
+		policy:     OverflowDrop,
+		errorLevel: slog.LevelError,
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	s := &asyncState{
+		conf:    conf,
+		queue:   make(chan asyncItem, conf.queueSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+
+	return &AsyncHandler{
+		next:  next,
+		state: s,
+	}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	s := h.state
+	if !s.enter() {
+		// Records logged after Close are handled synchronously.
+		return h.next.Handle(ctx, r)
+	}
+	defer s.senders.Done()
+
+	item := asyncItem{
+		next:   h.next,
+		ctx:    context.WithoutCancel(ctx),
+		record: r.Clone(),
+	}
+
+	if s.conf.policy == OverflowBlock || r.Level >= s.conf.errorLevel || recordHasError(r) {
+		select {
+		case s.queue <- item:
+			return nil
+		case <-s.closing:
+			// The queue is not drained after Close until senders are done,
+			// so the blocked record is handled synchronously.
+			return h.next.Handle(ctx, r)
+		}
+	}
+
+	select {
+	case s.queue <- item:
+	default:
+		s.dropped.Add(1)
+	}
+	return nil
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{
+		next:  h.next.WithAttrs(attrs),
+		state: h.state,
+	}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{
+		next:  h.next.WithGroup(name),
+		state: h.state,
+	}
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (h *AsyncHandler) Dropped() uint64 {
+	return h.state.dropped.Load()
+}
+
+// Flush waits until all records queued before the call are handled or the context is done.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	s := h.state
+	if !s.enter() {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case s.queue <- asyncItem{flushed: flushed}:
+		s.senders.Done()
+	case <-s.closing:
+		// Close handles all queued records.
+		s.senders.Done()
+		return nil
+	case <-ctx.Done():
+		s.senders.Done()
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting records into the queue and waits until queued
+// records are handled or the context is done.
+func (h *AsyncHandler) Close(ctx context.Context) error {
+	s := h.state
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.closing)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type asyncConfig struct {
+	queueSize  int
+	policy     OverflowPolicy
+	errorLevel slog.Level
+}
+
+type asyncItem struct {
+	next    slog.Handler
+	ctx     context.Context
+	record  slog.Record
+	flushed chan struct{}
+}
+
+type asyncState struct {
+	conf asyncConfig
+
+	// mu guards closed, the lock is not held while sending to the queue,
+	// senders are tracked instead, so Close is not blocked by a full queue.
+	mu      sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup
+
+	queue   chan asyncItem
+	closing chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+// enter registers the sender and reports whether the handler is not closed,
+// the sender must call senders.Done when it is done with the queue.
+func (s *asyncState) enter() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false
+	}
+	s.senders.Add(1)
+	return true
+}
+
+func (s *asyncState) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case item := <-s.queue:
+			s.handle(item)
+		case <-s.closing:
+			// Records are handled until senders which started before Close are done,
+			// then the rest of the queue is drained.
+			sendersDone := make(chan struct{})
+			go func() {
+				s.senders.Wait()
+				close(sendersDone)
+			}()
+			for {
+				select {
+				case item := <-s.queue:
+					s.handle(item)
+				case <-sendersDone:
+					for {
+						select {
+						case item := <-s.queue:
+							s.handle(item)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *asyncState) handle(item asyncItem) {
+	if item.flushed != nil {
+		close(item.flushed)
+		return
+	}
+	_ = item.next.Handle(item.ctx, item.record)
+}
@@ -0,0 +1,146 @@
+package loghelper_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+// blockingHandler records messages and blocks until released.
+type blockingHandler struct {
+	mu       sync.Mutex
+	entered  chan struct{}
+	release  chan struct{}
+	messages []string
+}
+
+func (h *blockingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *blockingHandler) Handle(_ context.Context, r slog.Record) error {
+	select {
+	case h.entered <- struct{}{}:
+	default:
+	}
+	<-h.release
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, r.Message)
+	return nil
+}
+
+func (h *blockingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *blockingHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestAsyncHandler(t *testing.T) {
+
+	var (
+		ctx  = context.Background()
+		next = &blockingHandler{
+			entered: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		h = loghelper.NewAsyncHandler(next,
+			loghelper.WithQueueSize(2),
+		)
+		logger = slog.New(h)
+	)
+
+	// the first record is taken by the background goroutine, two more fill the queue
+	logger.Info("1")
+	<-next.entered
+	for _, msg := range []string{"2", "3", "4", "5"} {
+		logger.Info(msg)
+	}
+
+	errLogged := make(chan struct{})
+	go func() {
+		defer close(errLogged)
+		logger.Info("failure", loghelper.Attr(serror.New("error", "a", "b")))
+	}()
+
+	close(next.release)
+	<-errLogged
+	assert.NoError(t, h.Flush(ctx))
+	assert.Equal(t, []string{"1", "2", "3", "failure"}, next.messages)
+	assert.Equal(t, uint64(2), h.Dropped())
+
+	assert.NoError(t, h.Close(ctx))
+	logger.Info("after close")
+	assert.Equal(t, "after close", next.messages[len(next.messages)-1])
+}
+
+func TestAsyncHandlerCloseBlocked(t *testing.T) {
+
+	var (
+		next = &blockingHandler{
+			entered: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		h = loghelper.NewAsyncHandler(next,
+			loghelper.WithQueueSize(1),
+			loghelper.WithOverflowPolicy(loghelper.OverflowBlock),
+		)
+		logger = slog.New(h)
+	)
+
+	logger.Info("1")
+	<-next.entered
+	logger.Info("2")
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		logger.Info("3")
+	}()
+
+	// Close is not blocked by the sender waiting for the full queue.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, h.Close(ctx), context.DeadlineExceeded)
+
+	close(next.release)
+	<-blocked
+	assert.NoError(t, h.Close(context.Background()))
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, next.messages)
+}
+
+func BenchmarkJSONHandler(b *testing.B) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	benchmarkLogger(b, logger)
+}
+
+func BenchmarkAsyncHandler(b *testing.B) {
+	h := loghelper.NewAsyncHandler(slog.NewJSONHandler(io.Discard, nil),
+		loghelper.WithOverflowPolicy(loghelper.OverflowBlock),
+	)
+	defer h.Close(context.Background())
+	benchmarkLogger(b, slog.New(h))
+}
+
+func BenchmarkAsyncHandlerDrop(b *testing.B) {
+	h := loghelper.NewAsyncHandler(slog.NewJSONHandler(io.Discard, nil))
+	defer h.Close(context.Background())
+	benchmarkLogger(b, slog.New(h))
+}
+
+func benchmarkLogger(b *testing.B, logger *slog.Logger) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("request handled",
+				slog.String("route", "/user"),
+				slog.Int("status", 200),
+				slog.Group("request", slog.String("id", "b4133182-89a6-11ee-b9d1-0242ac120002")),
+			)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package loghelper
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/vovanec/serror/internal"
+)
+
+// ComponentKey is the log attribute key identifying the component which produced the record.
+const ComponentKey = "component"
+
+// Logger returns the logger tagging records with the component, which passes records to
+// the handler of the default logger at the time they are logged, so loggers created at the
+// package level before InitLogging is called use the configured handler and levels.
+func Logger(component string) *slog.Logger {
+	return slog.New(new(defaultHandler)).With(ComponentKey, component)
+}
+
+// WithComponentLevel sets the log level of records tagged with the given component
+// as the value of the ComponentKey attribute. Components are hierarchical, dot separated
+// names, the level of the longest configured prefix applies, e.g. the "db" level
+// applies to the "db.pool" component unless "db.pool" level is set. Component levels apply to the main log output,
+// additional outputs added with WithSink have their own levels.
+func WithComponentLevel(component string, level slog.Level) LogOption {
+	return func(c *logConfig) {
+		if c.components == nil {
+			c.components = make(map[string]slog.Level)
+		}
+		c.components[component] = level
+	}
+}
+
+// componentHandler filters records by the level of the component they are tagged with.
+type componentHandler struct {
+	next         slog.Handler
+	levels       *LevelRegistry
+	component    string
+	hasComponent bool
+	groups       bool
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.hasComponent {
+		return level >= h.levels.level(h.component, true) && h.next.Enabled(ctx, level)
+	} else if component, ok := contextComponent(ctx); ok {
+		return level >= h.levels.level(component, true) && h.next.Enabled(ctx, level)
+	}
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	component, ok := h.component, h.hasComponent
+	if !h.groups {
+		recordAttrs(r, func(a slog.Attr) bool {
+			if a.Key == ComponentKey {
+				component, ok = a.Value.Resolve().String(), true
+				return false
+			}
+			return true
+		})
+	}
+	if !ok && ctx != nil {
+		component, ok = contextComponent(ctx)
+	}
+
+	if r.Level < h.levels.level(component, ok) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	ret := *h
+	ret.next = h.next.WithAttrs(attrs)
+	if !h.groups {
+		for _, a := range attrs {
+			inlineAttrs(a, func(a slog.Attr) bool {
+				if a.Key == ComponentKey {
+					ret.component, ret.hasComponent = a.Value.Resolve().String(), true
+				}
+				return true
+			})
+		}
+	}
+	return &ret
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	ret := *h
+	ret.next = h.next.WithGroup(name)
+	ret.groups = h.groups || name != ""
+	return &ret
+}
+
+// defaultHandler passes records to the handler of the current default logger,
+// with attributes and groups added to the defaultHandler applied to it.
+type defaultHandler struct {
+	derive []func(h slog.Handler) slog.Handler
+	cache  atomic.Pointer[derivedHandler]
+}
+
+// derivedHandler is the handler derived from the base default logger handler.
+type derivedHandler struct {
+	base    slog.Handler
+	handler slog.Handler
+}
+
+func (h *defaultHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *defaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *defaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) < 1 {
+		return h
+	}
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithAttrs(attrs)
+	})
+}
+
+func (h *defaultHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithGroup(name)
+	})
+}
+
+func (h *defaultHandler) with(f func(h slog.Handler) slog.Handler) *defaultHandler {
+	return &defaultHandler{
+		derive: append(h.derive[:len(h.derive):len(h.derive)], f),
+	}
+}
+
+// handler returns the handler derived from the default logger handler, the derived
+// handler is cached until the default logger handler is changed.
+func (h *defaultHandler) handler() slog.Handler {
+	base := slog.Default().Handler()
+	comparable := reflect.TypeOf(base).Comparable()
+	if d := h.cache.Load(); d != nil && comparable && d.base == base {
+		return d.handler
+	}
+
+	ret := base
+	for _, f := range h.derive {
+		ret = f(ret)
+	}
+	if comparable {
+		h.cache.Store(&derivedHandler{base: base, handler: ret})
+	}
+	return ret
+}
+
+// contextComponent returns the component attached to the context with Context.
+func contextComponent(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if a, ok := internal.LogAttrFromContext(ctx, ComponentKey); ok {
+		return a.Value.Resolve().String(), true
+	}
+	return "", false
+}
+
+// parentComponent returns the parent of the hierarchical component name.
+func parentComponent(component string) (string, bool) {
+	if i := strings.LastIndexByte(component, '.'); i >= 0 {
+		return component[:i], true
+	}
+	return "", false
+}
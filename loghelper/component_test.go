@@ -0,0 +1,59 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror/loghelper"
+)
+
+// dbLogger is created before the default logger is initialized.
+var dbLogger = loghelper.Logger("db")
+
+func TestComponentLevels(t *testing.T) {
+
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+	})
+
+	var buf bytes.Buffer
+	loghelper.InitLogging(
+		loghelper.WithOutput(&buf),
+		loghelper.WithFormat(loghelper.FormatText),
+		loghelper.WithComponentLevel("db", slog.LevelDebug),
+		loghelper.WithComponentLevel("db.pool", slog.LevelWarn),
+	)
+
+	var (
+		levels = loghelper.DefaultLevels()
+		ctx    = context.Background()
+	)
+	assert.Equal(t, slog.LevelDebug, levels.ComponentLevel("db.query"))
+	assert.Equal(t, slog.LevelWarn, levels.ComponentLevel("db.pool.conn"))
+	assert.Equal(t, slog.LevelInfo, levels.ComponentLevel("http"))
+	assert.Equal(t, slog.LevelInfo, levels.ComponentLevel("dbx"))
+
+	assert.True(t, loghelper.Logger("db.query").Enabled(ctx, slog.LevelDebug))
+	assert.False(t, loghelper.Logger("db.pool").Enabled(ctx, slog.LevelInfo))
+	assert.False(t, loghelper.Logger("http").Enabled(ctx, slog.LevelDebug))
+
+	loghelper.Logger("db.query").Debug("query executed")
+	loghelper.Logger("db.pool").Info("connection acquired")
+	assert.Contains(t, buf.String(), `msg="query executed" component=db.query`)
+	assert.NotContains(t, buf.String(), "connection acquired")
+
+	dbLogger.With("table", "users").Debug("package logger")
+	assert.Contains(t, buf.String(), `msg="package logger" component=db table=users`)
+
+	// component can be attached to the context as well
+	buf.Reset()
+	ctx = loghelper.Context(ctx, loghelper.ComponentKey, "db")
+	slog.DebugContext(ctx, "debug in context")
+	slog.Debug("debug without context")
+	assert.Contains(t, buf.String(), "debug in context")
+	assert.NotContains(t, buf.String(), "debug without context")
+}
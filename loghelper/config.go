@@ -0,0 +1,271 @@
+package loghelper
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vovanec/serror"
+)
+
+// EnvPrefix is the prefix of environment variables InitFromEnv reads the logging
+// configuration from. The rest of the variable name is lowercased and underscores
+// are replaced with dots to get the configuration key, e.g. SERROR_LOG_LEVEL_DB
+// corresponds to the level.db key.
+const EnvPrefix = "SERROR_LOG_"
+
+const (
+	configLevelKey    = "level"
+	configFormatKey   = "format"
+	configOutputKey   = "output"
+	configSourceKey   = "source"
+	configRedactKey   = "redact"
+	configSamplingKey = "sampling"
+)
+
+// InitFromEnv initializes default slog logger from SERROR_LOG_* environment variables.
+// See InitFromConfig for the list of supported settings.
+func InitFromEnv() (*slog.Logger, *slog.LevelVar, error) {
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, levelVar := InitLogging(opts...)
+	return logger, levelVar, nil
+}
+
+// InitFromConfig initializes default slog logger from the configuration of "key = value"
+// lines, empty lines and lines starting with # are ignored. Supported keys are:
+//
+//	level = debug|info|warn|error          default log level
+//	level.<component> = <level>            log level of the component
+//	format = json|text                     log output format
+//	output = stderr|stdout|<file path>     log output
+//	source = true|false                    add source code position
+//	redact = <key>[,<key>...]              attributes with redacted values
+//	sampling.<level> = first=N,thereafter=M[,interval=D][,key=K]
+func InitFromConfig(r io.Reader) (*slog.Logger, *slog.LevelVar, error) {
+	opts, err := OptionsFromConfig(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, levelVar := InitLogging(opts...)
+	return logger, levelVar, nil
+}
+
+// OptionsFromEnv returns log options built from SERROR_LOG_* environment variables.
+func OptionsFromEnv() ([]LogOption, error) {
+	var entries []configEntry
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		entries = append(entries, configEntry{
+			key:   strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), "_", "."),
+			value: value,
+			attrs: []any{slog.String("env", name)},
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	return configOptions(entries, nil)
+}
+
+// OptionsFromConfig returns log options built from the configuration, see InitFromConfig.
+func OptionsFromConfig(r io.Reader) ([]LogOption, error) {
+	var (
+		entries []configEntry
+		errs    []error
+		scanner = bufio.NewScanner(r)
+	)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, serror.New("invalid log config line, expected key = value",
+				slog.Int("line", n),
+				slog.String("text", line),
+			))
+			continue
+		}
+		entries = append(entries, configEntry{
+			key:   strings.TrimSpace(key),
+			value: strings.TrimSpace(value),
+			attrs: []any{slog.Int("line", n)},
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, serror.Wrap(err, "error reading log config")
+	}
+
+	return configOptions(entries, errs)
+}
+
+type configEntry struct {
+	key   string
+	value string
+	attrs []any
+}
+
+// invalid returns the error pointing at the invalid configuration entry.
+func (e configEntry) invalid(msg string, args ...any) error {
+	return serror.New(msg,
+		append([]any{
+			slog.String("key", e.key),
+			slog.String("value", e.value),
+		}, append(e.attrs, args...)...)...,
+	)
+}
+
+// configOptions returns options built from the entries, or the error joining errs and
+// errors of invalid entries. Output files are opened only if all entries are valid.
+func configOptions(entries []configEntry, errs []error) ([]LogOption, error) {
+
+	var (
+		opts  []LogOption
+		files = make(map[int]configEntry)
+	)
+
+	for _, e := range entries {
+		if isOutputFile(e) {
+			files[len(opts)] = e
+			opts = append(opts, nil)
+			continue
+		}
+		opt, err := configOption(e)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var opened []*os.File
+	for i, e := range files {
+		f, err := os.OpenFile(e.value, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			for _, f := range opened {
+				_ = f.Close()
+			}
+			return nil, e.invalid("error opening log output", slog.String("reason", err.Error()))
+		}
+		opened = append(opened, f)
+		opts[i] = WithOutput(f)
+	}
+	return opts, nil
+}
+
+// isOutputFile reports whether the entry sets the log output to the file.
+func isOutputFile(e configEntry) bool {
+	return e.key == configOutputKey && e.value != "stderr" && e.value != "stdout" && e.value != ""
+}
+
+func configOption(e configEntry) (LogOption, error) {
+
+	key, sub, _ := strings.Cut(e.key, ".")
+
+	switch {
+	case key == configLevelKey:
+		level, err := parseLevel(e.value)
+		if err != nil {
+			return nil, e.invalid("invalid log level")
+		}
+		if sub != "" {
+			return WithComponentLevel(sub, level), nil
+		}
+		return WithLevel(level), nil
+	case key == configFormatKey && sub == "":
+		switch strings.ToLower(e.value) {
+		case "json":
+			return WithFormat(FormatJSON), nil
+		case "text":
+			return WithFormat(FormatText), nil
+		}
+		return nil, e.invalid("invalid log format, expected json or text")
+	case key == configOutputKey && sub == "":
+		// Output files are opened by configOptions once all entries are valid.
+		if e.value == "stdout" {
+			return WithOutput(os.Stdout), nil
+		}
+		return WithOutput(os.Stderr), nil
+	case key == configSourceKey && sub == "":
+		enabled, err := strconv.ParseBool(e.value)
+		if err != nil {
+			return nil, e.invalid("invalid boolean value")
+		}
+		return WithSource(enabled), nil
+	case key == configRedactKey && sub == "":
+		var keys []string
+		for _, k := range strings.Split(e.value, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+		return WithRedact(keys...), nil
+	case key == configSamplingKey && sub != "":
+		level, err := parseLevel(sub)
+		if err != nil {
+			return nil, e.invalid("invalid sampling level")
+		}
+		p, err := parseSamplingPolicy(e.value)
+		if err != nil {
+			return nil, e.invalid("invalid sampling policy", slog.String("reason", err.Error()))
+		}
+		return WithSampling(WithSamplingPolicy(level, p)), nil
+	}
+
+	return nil, e.invalid("unknown log config key")
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return level, err
+}
+
+func parseSamplingPolicy(s string) (SamplingPolicy, error) {
+	var p SamplingPolicy
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return p, errors.New("expected name=value: " + part)
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch strings.TrimSpace(name) {
+		case "first":
+			p.First, err = strconv.Atoi(value)
+		case "thereafter":
+			p.Thereafter, err = strconv.Atoi(value)
+		case "interval":
+			p.Interval, err = time.ParseDuration(value)
+		case "key":
+			p.Key = value
+		default:
+			err = errors.New("unknown sampling parameter: " + name)
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
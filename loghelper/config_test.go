@@ -0,0 +1,108 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func TestOptionsFromConfig(t *testing.T) {
+
+	opts, err := loghelper.OptionsFromConfig(strings.NewReader(`
+# logging configuration
+level = warn
+level.db = debug
+format = text
+redact = password, token
+sampling.info = first=1, thereafter=0
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	logger, _ := loghelper.NewLogger(append(opts, loghelper.WithOutput(&buf))...)
+
+	logger.Info("not logged")
+	logger.Debug("db query", loghelper.ComponentKey, "db", "password", "secret")
+	logger.With(loghelper.ComponentKey, "db").Info("db connected")
+	logger.With(loghelper.ComponentKey, "db").Info("db connected")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), `msg="db query" component=db password=[REDACTED]`)
+	assert.Contains(t, buf.String(), `msg="db connected" component=db`)
+}
+
+func TestOptionsFromConfigErrors(t *testing.T) {
+
+	_, err := loghelper.OptionsFromConfig(strings.NewReader(`
+level = verbose
+format = xml
+colors = true
+`))
+	if assert.Error(t, err) {
+		var joined interface{ Unwrap() []error }
+		if assert.True(t, errors.As(err, &joined)) {
+			assert.Len(t, joined.Unwrap(), 3)
+		}
+		assert.Contains(t, err.Error(), "invalid log level")
+
+		var sErr slog.LogValuer
+		if assert.True(t, errors.As(err, &sErr)) {
+			attrs := map[string]string{}
+			for _, a := range sErr.LogValue().Group() {
+				attrs[a.Key] = a.Value.String()
+			}
+			assert.Equal(t, "level", attrs["key"])
+			assert.Equal(t, "verbose", attrs["value"])
+			assert.Equal(t, "2", attrs["line"])
+		}
+	}
+}
+
+func TestOptionsFromConfigOutput(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "app.log")
+
+	// The output file is not created if the configuration is rejected.
+	_, err := loghelper.OptionsFromConfig(strings.NewReader("output = " + name + "\nformat = xml\n"))
+	assert.ErrorContains(t, err, "invalid log format")
+	assert.NoFileExists(t, name)
+
+	opts, err := loghelper.OptionsFromConfig(strings.NewReader("output = " + name + "\nformat = text\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	logger, _ := loghelper.NewLogger(opts...)
+	logger.Info("written to file")
+
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `msg="written to file"`)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("SERROR_LOG_LEVEL", "debug")
+	t.Setenv("SERROR_LOG_LEVEL_DB_POOL", "error")
+
+	opts, err := loghelper.OptionsFromEnv()
+	if assert.NoError(t, err) {
+		var buf bytes.Buffer
+		logger, level := loghelper.NewLogger(append(opts, loghelper.WithOutput(&buf))...)
+		assert.Equal(t, slog.LevelDebug, level.Level())
+
+		logger.Warn("pool exhausted", loghelper.ComponentKey, "db.pool")
+		assert.Empty(t, buf.String())
+	}
+
+	t.Setenv("SERROR_LOG_FORMAT", "yaml")
+	_, err = loghelper.OptionsFromEnv()
+	assert.ErrorContains(t, err, "invalid log format")
+}
@@ -0,0 +1,227 @@
+package loghelper
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/vovanec/serror/internal"
+)
+
+type DebugBufferOption func(c *debugBufferConfig)
+
+// WithBufferSize sets the number of recent records kept per request, 100 by default.
+// Sizes less than 1 are treated as 1.
+func WithBufferSize(n int) DebugBufferOption {
+	return func(c *debugBufferConfig) {
+		c.size = n
+	}
+}
+
+// WithMaxRequests sets the number of requests records are buffered for, 1024 by default.
+// Buffers of the oldest requests are discarded when the limit is reached.
+// Limits less than 1 are treated as 1.
+func WithMaxRequests(n int) DebugBufferOption {
+	return func(c *debugBufferConfig) {
+		c.maxRequests = n
+	}
+}
+
+// WithRequestKey sets the key of the context log attribute identifying the request,
+// nested group attributes are addressed with dot separated keys. Default is "request.id".
+func WithRequestKey(key string) DebugBufferOption {
+	return func(c *debugBufferConfig) {
+		c.requestKey = key
+	}
+}
+
+// DebugBufferHandler is the slog.Handler middleware which keeps the last records
+// the next handler is not enabled for, usually debug ones, per request identified by
+// the log attribute attached to the context with Context. When an error level record or
+// a record carrying an error is logged in the same request, buffered records are passed
+// to the next handler first. Otherwise, the buffer is discarded by Discard.
+type DebugBufferHandler struct {
+	next  slog.Handler
+	state *debugBufferState
+}
+
+// NewDebugBufferHandler returns the debug buffer handler passing records to the next handler.
+func NewDebugBufferHandler(next slog.Handler, opts ...DebugBufferOption) *DebugBufferHandler {
+
+	conf := debugBufferConfig{
+		size:        100,
+		maxRequests: 1024,
+		requestKey:  "request.id",
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	conf.size = max(conf.size, 1)
+	conf.maxRequests = max(conf.maxRequests, 1)
+
+	return &DebugBufferHandler{
+		next: next,
+		state: &debugBufferState{
+			conf:     conf,
+			requests: make(map[string]*list.Element),
+			order:    list.New(),
+		},
+	}
+}
+
+func (h *DebugBufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.next.Enabled(ctx, level) {
+		return true
+	}
+	_, ok := h.state.requestID(ctx)
+	return ok
+}
+
+func (h *DebugBufferHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	id, ok := h.state.requestID(ctx)
+
+	if !h.next.Enabled(ctx, r.Level) {
+		if ok {
+			h.state.add(id, h.next, r)
+		}
+		return nil
+	}
+
+	if ok && (r.Level >= slog.LevelError || recordHasError(r)) {
+		for _, br := range h.state.take(id) {
+			if err := br.next.Handle(ctx, br.record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *DebugBufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &DebugBufferHandler{
+		next:  h.next.WithAttrs(attrs),
+		state: h.state,
+	}
+}
+
+func (h *DebugBufferHandler) WithGroup(name string) slog.Handler {
+	return &DebugBufferHandler{
+		next:  h.next.WithGroup(name),
+		state: h.state,
+	}
+}
+
+// Discard discards records buffered for the request the context belongs to,
+// it should be called when the request is finished.
+func (h *DebugBufferHandler) Discard(ctx context.Context) {
+	if id, ok := h.state.requestID(ctx); ok {
+		h.state.take(id)
+	}
+}
+
+type debugBufferConfig struct {
+	size        int
+	maxRequests int
+	requestKey  string
+}
+
+type bufferedRecord struct {
+	next   slog.Handler
+	record slog.Record
+}
+
+// recordRing keeps the last records of the request.
+type recordRing struct {
+	id      string
+	records []bufferedRecord
+	start   int
+}
+
+func (r *recordRing) add(br bufferedRecord, size int) {
+	if len(r.records) < size {
+		r.records = append(r.records, br)
+		return
+	}
+	r.records[r.start] = br
+	r.start = (r.start + 1) % size
+}
+
+func (r *recordRing) ordered() []bufferedRecord {
+	ret := make([]bufferedRecord, 0, len(r.records))
+	ret = append(ret, r.records[r.start:]...)
+	return append(ret, r.records[:r.start]...)
+}
+
+type debugBufferState struct {
+	mu       sync.Mutex
+	conf     debugBufferConfig
+	requests map[string]*list.Element
+	order    *list.List
+}
+
+func (s *debugBufferState) requestID(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	key, path, _ := strings.Cut(s.conf.requestKey, ".")
+	a, ok := internal.LogAttrFromContext(ctx, key)
+	for ok && path != "" {
+		key, path, _ = strings.Cut(path, ".")
+		ok = false
+		if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+			for _, ga := range v.Group() {
+				if ga.Key == key {
+					a, ok = ga, true
+					break
+				}
+			}
+		}
+	}
+
+	if !ok {
+		return "", false
+	}
+	return a.Value.Resolve().String(), true
+}
+
+func (s *debugBufferState) add(id string, next slog.Handler, r slog.Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.requests[id]
+	if !ok {
+		if s.order.Len() >= s.conf.maxRequests {
+			oldest := s.order.Front()
+			s.order.Remove(oldest)
+			delete(s.requests, oldest.Value.(*recordRing).id)
+		}
+		e = s.order.PushBack(&recordRing{id: id})
+		s.requests[id] = e
+	}
+
+	e.Value.(*recordRing).add(bufferedRecord{
+		next:   next,
+		record: r.Clone(),
+	}, s.conf.size)
+}
+
+// take removes the request buffer and returns buffered records in the order they were logged.
+func (s *debugBufferState) take(id string) []bufferedRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.requests[id]
+	if !ok {
+		return nil
+	}
+	s.order.Remove(e)
+	delete(s.requests, id)
+
+	return e.Value.(*recordRing).ordered()
+}
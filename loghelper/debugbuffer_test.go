@@ -0,0 +1,111 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func TestDebugBufferHandler(t *testing.T) {
+
+	var (
+		buf bytes.Buffer
+		h   = loghelper.NewDebugBufferHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithBufferSize(2),
+		)
+		logger = slog.New(h)
+		ctx1   = loghelper.Context(context.Background(), slog.Group("request", slog.String("id", "1")))
+		ctx2   = loghelper.Context(context.Background(), slog.Group("request", slog.String("id", "2")))
+	)
+
+	for _, msg := range []string{"debug 1", "debug 2", "debug 3"} {
+		logger.DebugContext(ctx1, msg, loghelper.Attr(ctx1))
+		logger.DebugContext(ctx2, msg, loghelper.Attr(ctx2))
+	}
+	logger.Debug("no request")
+	logger.InfoContext(ctx1, "info")
+
+	lines := decodeLines(t, &buf)
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "info", lines[0]["msg"])
+	}
+
+	buf.Reset()
+	logger.ErrorContext(ctx1, "request failed", loghelper.Attr(ctx1, serror.New("error", "a", "b")))
+
+	lines = decodeLines(t, &buf)
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "debug 2", lines[0]["msg"])
+		assert.Equal(t, "debug 3", lines[1]["msg"])
+		assert.Equal(t, map[string]any{"id": "1"}, lines[1]["request"])
+		assert.Equal(t, "request failed", lines[2]["msg"])
+	}
+
+	buf.Reset()
+	h.Discard(ctx2)
+	logger.ErrorContext(ctx2, "request failed")
+	assert.Len(t, decodeLines(t, &buf), 1)
+}
+
+func TestDebugBufferHandlerDerivedContext(t *testing.T) {
+
+	var (
+		buf    bytes.Buffer
+		h      = loghelper.NewDebugBufferHandler(slog.NewJSONHandler(&buf, nil))
+		logger = slog.New(h)
+		base   = loghelper.Context(context.Background(), "app", "test")
+		ctx1   = loghelper.Context(base, slog.Group("request", slog.String("id", "1")))
+		ctx2   = loghelper.Context(base, slog.Group("request", slog.String("id", "2")))
+	)
+
+	logger.DebugContext(ctx1, "debug 1")
+	logger.DebugContext(ctx2, "debug 2")
+	logger.ErrorContext(ctx1, "request failed")
+
+	lines := decodeLines(t, &buf)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "debug 1", lines[0]["msg"])
+	}
+}
+
+type requestInfo struct {
+	id string
+}
+
+func (r requestInfo) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", r.id))
+}
+
+func TestDebugBufferHandlerLimits(t *testing.T) {
+
+	var (
+		buf bytes.Buffer
+		h   = loghelper.NewDebugBufferHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithBufferSize(0),
+			loghelper.WithMaxRequests(0),
+		)
+		logger = slog.New(h)
+		ctx1   = loghelper.Context(context.Background(), slog.Any("request", requestInfo{id: "1"}))
+		ctx2   = loghelper.Context(context.Background(), slog.Any("request", requestInfo{id: "2"}))
+	)
+
+	logger.DebugContext(ctx1, "debug 1")
+	logger.DebugContext(ctx2, "debug 2")
+	logger.DebugContext(ctx2, "debug 3")
+	logger.ErrorContext(ctx1, "request 1 failed")
+	logger.ErrorContext(ctx2, "request 2 failed")
+
+	lines := decodeLines(t, &buf)
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "request 1 failed", lines[0]["msg"])
+		assert.Equal(t, "debug 3", lines[1]["msg"])
+		assert.Equal(t, "request 2 failed", lines[2]["msg"])
+	}
+}
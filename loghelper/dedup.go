@@ -0,0 +1,262 @@
+package loghelper
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const suppressedCountKey = "suppressed_count"
+
+type DedupOption func(c *dedupConfig)
+
+// WithDedupWindow sets the time window in which repeated records are suppressed.
+func WithDedupWindow(window time.Duration) DedupOption {
+	return func(c *dedupConfig) {
+		c.window = window
+	}
+}
+
+// WithDedupClock sets the function returning current time, mostly useful in tests.
+func WithDedupClock(now func() time.Time) DedupOption {
+	return func(c *dedupConfig) {
+		c.now = now
+	}
+}
+
+// WithDedupKey sets the function computing the deduplication key of the record.
+// By default, records are keyed by the error fingerprint if available or by
+// error message and origin otherwise. Records of loggers with different attributes
+// or groups, see slog.Logger.With, are never deduplicated together.
+func WithDedupKey(key func(r slog.Record) string) DedupOption {
+	return func(c *dedupConfig) {
+		c.key = key
+	}
+}
+
+// WithDedupAll makes the handler deduplicate all records, not only ones carrying errors.
+func WithDedupAll() DedupOption {
+	return func(c *dedupConfig) {
+		c.all = true
+	}
+}
+
+// DedupStats contains deduplication handler counters.
+type DedupStats struct {
+	Passed     uint64
+	Suppressed uint64
+	Summaries  uint64
+}
+
+// DedupHandler is the slog.Handler middleware which suppresses repeats of the same error
+// within the time window. When the window is over, a copy of the first record with the
+// suppressed_count attribute is emitted if any repeats were suppressed.
+type DedupHandler struct {
+	next slog.Handler
+	// scope identifies attributes and groups added to the handler.
+	scope string
+	state *dedupState
+}
+
+// NewDedupHandler returns the deduplication handler passing records to the next handler.
+// Default window is one minute.
+func NewDedupHandler(next slog.Handler, opts ...DedupOption) *DedupHandler {
+
+	conf := dedupConfig{
+		window: time.Minute,
+		now:    time.Now,
+		key:    recordKey,
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return &DedupHandler{
+		next: next,
+		state: &dedupState{
+			conf:    conf,
+			entries: make(map[string]*dedupEntry),
+		},
+	}
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	if !h.state.conf.all && !recordHasError(r) {
+		return h.next.Handle(ctx, r)
+	}
+
+	summaries, pass := h.state.track(h.next, h.scope, r)
+	if err := emitSummaries(summaries); err != nil {
+		return err
+	}
+	if !pass {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scope := h.scope
+	for _, a := range attrs {
+		scope += " " + slog.Attr{Key: a.Key, Value: a.Value.Resolve()}.String()
+	}
+	return &DedupHandler{
+		next:  h.next.WithAttrs(attrs),
+		scope: scope,
+		state: h.state,
+	}
+}
+
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	return &DedupHandler{
+		next:  h.next.WithGroup(name),
+		scope: h.scope + " " + name + ".",
+		state: h.state,
+	}
+}
+
+// Flush emits summary records for all suppressed records, including ones
+// whose deduplication window is not over yet, and resets the state.
+func (h *DedupHandler) Flush(context.Context) error {
+	return emitSummaries(h.state.sweep(true))
+}
+
+// Run emits summary records for expired windows every interval until the context is done.
+func (h *DedupHandler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = emitSummaries(h.state.sweep(false))
+		}
+	}
+}
+
+// Stats returns the deduplication counters.
+func (h *DedupHandler) Stats() DedupStats {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	return h.state.stats
+}
+
+type dedupConfig struct {
+	window time.Duration
+	now    func() time.Time
+	key    func(r slog.Record) string
+	all    bool
+}
+
+type dedupEntry struct {
+	next       slog.Handler
+	record     slog.Record
+	start      time.Time
+	suppressed int
+}
+
+type dedupState struct {
+	mu        sync.Mutex
+	conf      dedupConfig
+	entries   map[string]*dedupEntry
+	lastSweep time.Time
+	stats     DedupStats
+}
+
+type dedupSummary struct {
+	next   slog.Handler
+	record slog.Record
+}
+
+// track registers the record and reports whether it should be passed to the next handler.
+// It also returns summaries for the windows which are over.
+func (s *dedupState) track(next slog.Handler, scope string, r slog.Record) ([]dedupSummary, bool) {
+
+	key := scope + "\x00" + s.conf.key(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.conf.now()
+
+	var summaries []dedupSummary
+	if now.Sub(s.lastSweep) >= s.conf.window {
+		summaries = s.sweepLocked(now, false)
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[key]; ok && now.Sub(e.start) < s.conf.window {
+		e.suppressed++
+		s.stats.Suppressed++
+		return summaries, false
+	} else if ok {
+		if sum, ok := s.summaryLocked(now, e); ok {
+			summaries = append(summaries, sum)
+		}
+	}
+
+	s.entries[key] = &dedupEntry{
+		next:   next,
+		record: r.Clone(),
+		start:  now,
+	}
+	s.stats.Passed++
+
+	return summaries, true
+}
+
+func (s *dedupState) sweep(all bool) []dedupSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sweepLocked(s.conf.now(), all)
+}
+
+func (s *dedupState) sweepLocked(now time.Time, all bool) []dedupSummary {
+	var summaries []dedupSummary
+	for key, e := range s.entries {
+		if !all && now.Sub(e.start) < s.conf.window {
+			continue
+		}
+		if sum, ok := s.summaryLocked(now, e); ok {
+			summaries = append(summaries, sum)
+		}
+		delete(s.entries, key)
+	}
+	return summaries
+}
+
+func (s *dedupState) summaryLocked(now time.Time, e *dedupEntry) (dedupSummary, bool) {
+	if e.suppressed < 1 {
+		return dedupSummary{}, false
+	}
+
+	r := slog.NewRecord(now, e.record.Level, e.record.Message, e.record.PC)
+	e.record.Attrs(func(a slog.Attr) bool {
+		r.AddAttrs(a)
+		return true
+	})
+	r.AddAttrs(slog.Int(suppressedCountKey, e.suppressed))
+
+	s.stats.Summaries++
+	return dedupSummary{next: e.next, record: r}, true
+}
+
+// emitSummaries emits summary records with the background context, since they are
+// triggered by unrelated records, whose context attributes must not be inherited.
+func emitSummaries(summaries []dedupSummary) error {
+	for _, sum := range summaries {
+		if err := sum.next.Handle(context.Background(), sum.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var ret []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		ret = append(ret, m)
+	}
+	return ret
+}
+
+func TestDedupHandler(t *testing.T) {
+
+	var (
+		buf   bytes.Buffer
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		h     = loghelper.NewDedupHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithDedupWindow(time.Minute),
+			loghelper.WithDedupClock(clock.Now),
+		)
+		logger = slog.New(h)
+	)
+
+	failure := func(id int) error {
+		return serror.New("dependency failed", slog.Int("id", id))
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Error("request failed", loghelper.Attr(failure(i)))
+		logger.Info("not an error")
+	}
+
+	lines := decodeLines(t, &buf)
+	assert.Len(t, lines, 6)
+	assert.Equal(t, loghelper.DedupStats{Passed: 1, Suppressed: 4}, h.Stats())
+
+	clock.Advance(time.Minute)
+	buf.Reset()
+	logger.Error("request failed", loghelper.Attr(failure(10)))
+
+	lines = decodeLines(t, &buf)
+	if assert.Len(t, lines, 2) {
+		assert.EqualValues(t, 4, lines[0]["suppressed_count"])
+		assert.EqualValues(t, 0, lines[0]["id"])
+		assert.Nil(t, lines[1]["suppressed_count"])
+		assert.EqualValues(t, 10, lines[1]["id"])
+	}
+
+	logger.Error("request failed", loghelper.Attr(failure(11)))
+	buf.Reset()
+	assert.NoError(t, h.Flush(context.Background()))
+
+	lines = decodeLines(t, &buf)
+	if assert.Len(t, lines, 1) {
+		assert.EqualValues(t, 1, lines[0]["suppressed_count"])
+	}
+	assert.Equal(t, loghelper.DedupStats{Passed: 2, Suppressed: 5, Summaries: 2}, h.Stats())
+}
+
+// ctxHandler records request IDs of contexts records are handled with.
+type ctxHandler struct {
+	slog.Handler
+	ids *[]any
+}
+
+type requestIDKey struct{}
+
+func (h ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	*h.ids = append(*h.ids, ctx.Value(requestIDKey{}))
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h ctxHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ctxHandler{Handler: h.Handler.WithAttrs(attrs), ids: h.ids}
+}
+
+func TestDedupHandlerScope(t *testing.T) {
+
+	var (
+		buf   bytes.Buffer
+		ids   []any
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		h     = loghelper.NewDedupHandler(
+			ctxHandler{Handler: slog.NewJSONHandler(&buf, nil), ids: &ids},
+			loghelper.WithDedupWindow(time.Minute),
+			loghelper.WithDedupClock(clock.Now),
+		)
+		logger = slog.New(h)
+		err    = serror.New("dependency failed", slog.Int("id", 1))
+	)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "first")
+	logger.With("component", "db").ErrorContext(ctx, "request failed", loghelper.Attr(err))
+	logger.With("component", "db").ErrorContext(ctx, "request failed", loghelper.Attr(err))
+	logger.With("component", "cache").ErrorContext(ctx, "request failed", loghelper.Attr(err))
+	logger.WithGroup("cache").ErrorContext(ctx, "request failed", loghelper.Attr(err))
+	assert.Equal(t, loghelper.DedupStats{Passed: 3, Suppressed: 1}, h.Stats())
+
+	clock.Advance(time.Minute)
+	ids = nil
+	buf.Reset()
+	ctx = context.WithValue(context.Background(), requestIDKey{}, "second")
+	logger.With("component", "db").ErrorContext(ctx, "request failed", loghelper.Attr(err))
+
+	lines := decodeLines(t, &buf)
+	if assert.Len(t, lines, 2) {
+		assert.EqualValues(t, 1, lines[0]["suppressed_count"])
+		assert.Equal(t, "db", lines[0]["component"])
+	}
+	assert.Equal(t, []any{nil, "second"}, ids)
+}
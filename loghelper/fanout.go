@@ -0,0 +1,56 @@
+package loghelper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// FanoutHandler is the slog.Handler which passes records to multiple handlers.
+// Each handler decides whether the record is enabled independently.
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// NewFanoutHandler returns the handler passing records to all the given handlers.
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hh := range h.handlers {
+		if !hh.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := hh.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, hh := range h.handlers {
+		handlers = append(handlers, hh.WithAttrs(attrs))
+	}
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, hh := range h.handlers {
+		handlers = append(handlers, hh.WithGroup(name))
+	}
+	return &FanoutHandler{handlers: handlers}
+}
@@ -39,12 +39,23 @@ func Context(ctx context.Context, args ...any) context.Context {
 	return internal.ContextWithLogArgs(ctx, args...)
 }
 
+const redactedValue = "[REDACTED]"
+
 type LogOption func(c *logConfig)
 
+// Format is the log output format.
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatText
+)
+
 // WithLevel sets default logger log level.
 func WithLevel(level slog.Level) LogOption {
 	return func(c *logConfig) {
 		c.level = level
+		c.levelSet = true
 	}
 }
 
@@ -55,28 +66,209 @@ func WithOutput(w io.Writer) LogOption {
 	}
 }
 
+// WithSink adds an additional log output with its own log level.
+func WithSink(w io.Writer, level slog.Leveler) LogOption {
+	return func(c *logConfig) {
+		c.sinks = append(c.sinks, logSink{output: w, level: level})
+	}
+}
+
+// WithFormat sets log output format, JSON by default.
+func WithFormat(f Format) LogOption {
+	return func(c *logConfig) {
+		c.format = f
+	}
+}
+
+// WithSource enables adding source code position of the log statement to the output.
+func WithSource(enabled bool) LogOption {
+	return func(c *logConfig) {
+		c.addSource = enabled
+	}
+}
+
+// WithReplaceAttr adds a function rewriting each non-group attribute before it is logged,
+// see slog.HandlerOptions. Multiple functions are applied in order.
+func WithReplaceAttr(f func(groups []string, a slog.Attr) slog.Attr) LogOption {
+	return func(c *logConfig) {
+		c.replaceAttr = append(c.replaceAttr, f)
+	}
+}
+
+// WithTimeFormat sets the layout the log record time is formatted with.
+func WithTimeFormat(layout string) LogOption {
+	return func(c *logConfig) {
+		c.timeFormat = layout
+	}
+}
+
+// WithKeyRename renames top-level attribute keys, e.g. slog.MessageKey to "message".
+func WithKeyRename(from, to string) LogOption {
+	return func(c *logConfig) {
+		if c.renames == nil {
+			c.renames = make(map[string]string)
+		}
+		c.renames[from] = to
+	}
+}
+
+// WithRedact replaces values of attributes with the given keys, at any
+// group level, with the "[REDACTED]" string.
+func WithRedact(keys ...string) LogOption {
+	return func(c *logConfig) {
+		redacted := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			redacted[k] = struct{}{}
+		}
+		c.replaceAttr = append(c.replaceAttr, func(_ []string, a slog.Attr) slog.Attr {
+			if _, ok := redacted[a.Key]; ok {
+				a.Value = slog.StringValue(redactedValue)
+			}
+			return a
+		})
+	}
+}
+
+// WithLevelRegistry sets the registry keeping log levels, so they can be changed at runtime.
+func WithLevelRegistry(r *LevelRegistry) LogOption {
+	return func(c *logConfig) {
+		c.levels = r
+	}
+}
+
+// WithSampling enables sampling of log records, see NewSamplingHandler.
+func WithSampling(opts ...SamplingOption) LogOption {
+	return func(c *logConfig) {
+		c.sampling = append(c.sampling, opts...)
+	}
+}
+
+// NewLogger builds the logger with info log level and stderr as a log output
+// by default. The returned level var can be used to change the output log level.
+func NewLogger(opts ...LogOption) (*slog.Logger, *slog.LevelVar) {
+	conf := newLogConfig(opts)
+	logger := conf.logger()
+	return logger, conf.levels.Default()
+}
+
 // InitLogging initializes default slog logger instance
 // with info log level and stderr as a log output.
-func InitLogging(opts ...LogOption) {
-	conf := logConfig{
+// The logger and its level var are returned, the level registry
+// is available through DefaultLevels.
+func InitLogging(opts ...LogOption) (*slog.Logger, *slog.LevelVar) {
+	conf := newLogConfig(opts)
+	logger := conf.logger()
+	slog.SetDefault(logger)
+	defaultLevels.Store(conf.levels)
+
+	return logger, conf.levels.Default()
+}
+
+// newLogConfig applies options to the default configuration.
+func newLogConfig(opts []LogOption) *logConfig {
+	conf := &logConfig{
 		level:  slog.LevelInfo,
 		output: os.Stderr,
 	}
 
 	for _, opt := range opts {
-		opt(&conf)
+		opt(conf)
+	}
+	return conf
+}
+
+// logger builds the logger, the level registry is created if it is not set.
+func (c *logConfig) logger() *slog.Logger {
+
+	if c.levels == nil {
+		c.levels = NewLevelRegistry(c.level)
+	} else if c.levelSet {
+		c.levels.set("", c.level)
+	}
+	for component, level := range c.components {
+		c.levels.set(component, level)
 	}
 
-	slog.SetDefault(
-		slog.New(
-			slog.NewJSONHandler(conf.output, &slog.HandlerOptions{
-				Level: conf.level,
-			}),
-		),
-	)
+	// Component levels apply to the main output only, additional
+	// outputs have their own levels.
+	handler := slog.Handler(&componentHandler{
+		next:   c.sampled(c.handler(c.output, c.levels)),
+		levels: c.levels,
+	})
+	if len(c.sinks) > 0 {
+		handlers := []slog.Handler{handler}
+		for _, sink := range c.sinks {
+			handlers = append(handlers, c.sampled(c.handler(sink.output, sink.level)))
+		}
+		handler = NewFanoutHandler(handlers...)
+	}
+
+	return slog.New(handler)
 }
 
-type logConfig struct {
-	level  slog.Level
+type logSink struct {
 	output io.Writer
+	level  slog.Leveler
+}
+
+type logConfig struct {
+	level       slog.Level
+	levelSet    bool
+	output      io.Writer
+	sinks       []logSink
+	format      Format
+	addSource   bool
+	replaceAttr []func(groups []string, a slog.Attr) slog.Attr
+	timeFormat  string
+	renames     map[string]string
+	sampling    []SamplingOption
+	components  map[string]slog.Level
+	levels      *LevelRegistry
+}
+
+func (c *logConfig) sampled(h slog.Handler) slog.Handler {
+	if len(c.sampling) > 0 {
+		return NewSamplingHandler(h, c.sampling...)
+	}
+	return h
+}
+
+func (c *logConfig) handler(w io.Writer, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{
+		AddSource:   c.addSource,
+		Level:       level,
+		ReplaceAttr: c.replaceAttrFunc(),
+	}
+	if c.format == FormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+func (c *logConfig) replaceAttrFunc() func(groups []string, a slog.Attr) slog.Attr {
+
+	if len(c.replaceAttr) < 1 && c.timeFormat == "" && len(c.renames) < 1 {
+		return nil
+	}
+
+	var (
+		replace    = c.replaceAttr
+		timeFormat = c.timeFormat
+		renames    = c.renames
+	)
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) < 1 && a.Key == slog.TimeKey && timeFormat != "" && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(a.Value.Time().Format(timeFormat))
+		}
+		for _, f := range replace {
+			a = f(groups, a)
+		}
+		if len(groups) < 1 {
+			if to, ok := renames[a.Key]; ok {
+				a.Key = to
+			}
+		}
+		return a
+	}
 }
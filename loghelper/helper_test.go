@@ -0,0 +1,45 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func TestNewLogger(t *testing.T) {
+
+	var (
+		out, debugOut bytes.Buffer
+	)
+
+	logger, level := loghelper.NewLogger(
+		loghelper.WithOutput(&out),
+		loghelper.WithSink(&debugOut, slog.LevelDebug),
+		loghelper.WithFormat(loghelper.FormatText),
+		loghelper.WithTimeFormat("2006"),
+		loghelper.WithKeyRename(slog.MessageKey, "message"),
+		loghelper.WithReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				a.Value = slog.StringValue("***")
+			}
+			return a
+		}),
+	)
+
+	logger.Debug("debug message")
+	logger.Info("info message", "password", "secret")
+
+	assert.NotContains(t, out.String(), "debug message")
+	assert.Contains(t, out.String(), `message="info message" password=***`)
+	assert.Contains(t, debugOut.String(), `message="debug message"`)
+	assert.Contains(t, debugOut.String(), `message="info message"`)
+	assert.Regexp(t, `^time=\d{4} level=DEBUG`, debugOut.String())
+
+	out.Reset()
+	level.Set(slog.LevelDebug)
+	logger.Debug("debug message")
+	assert.Contains(t, out.String(), "debug message")
+}
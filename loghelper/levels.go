@@ -0,0 +1,280 @@
+package loghelper
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelRegistry keeps the default log level and levels of named components,
+// which can be changed at runtime, optionally for a limited time.
+type LevelRegistry struct {
+	def *slog.LevelVar
+	// minLevel is the minimal level of all components, the default
+	// level var is not included since it can be changed directly.
+	minLevel atomic.Int64
+
+	// table is the immutable copy of component levels, which is replaced on
+	// changes, so levels are looked up without locking.
+	table atomic.Pointer[map[string]*slog.LevelVar]
+
+	mu      sync.RWMutex
+	levels  map[string]*slog.LevelVar
+	reverts map[string]*levelRevert
+}
+
+// levelRevert restores the level the component had before temporary changes.
+type levelRevert struct {
+	timer    *time.Timer
+	level    slog.Level
+	hadLevel bool
+}
+
+// NewLevelRegistry returns the registry with the given default log level.
+func NewLevelRegistry(level slog.Level) *LevelRegistry {
+	r := &LevelRegistry{
+		def:     new(slog.LevelVar),
+		levels:  make(map[string]*slog.LevelVar),
+		reverts: make(map[string]*levelRevert),
+	}
+	r.def.Set(level)
+	r.updateLocked()
+	return r
+}
+
+// levelComponentKey is used instead of ComponentKey when logging level changes,
+// so these records are not filtered by the level of the component being changed.
+const levelComponentKey = "target_component"
+
+var defaultLevels atomic.Pointer[LevelRegistry]
+
+// DefaultLevels returns the level registry of the logger initialized by InitLogging.
+func DefaultLevels() *LevelRegistry {
+	return defaultLevels.Load()
+}
+
+// Default returns the default log level var.
+func (r *LevelRegistry) Default() *slog.LevelVar {
+	return r.def
+}
+
+// Level returns the minimal level of all components, so the handler
+// filtering records by component gets all records it may need.
+func (r *LevelRegistry) Level() slog.Level {
+	return slog.Level(min(int64(r.def.Level()), r.minLevel.Load()))
+}
+
+// ComponentLevel returns the log level of records tagged with the component.
+func (r *LevelRegistry) ComponentLevel(component string) slog.Level {
+	return r.level(component, true)
+}
+
+// Levels returns a snapshot of component levels, the default level has an empty key.
+func (r *LevelRegistry) Levels() map[string]slog.Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ret := map[string]slog.Level{"": r.def.Level()}
+	for component, lv := range r.levels {
+		ret[component] = lv.Level()
+	}
+	return ret
+}
+
+// SetLevel sets the level of the component, or the default level if the component is empty.
+// If ttl is positive, the level is restored after ttl passes. The level restored is the one
+// the component had before the first of consecutive temporary changes, and every change
+// replaces the pending restore, so the last ttl wins and a change without ttl is permanent.
+func (r *LevelRegistry) SetLevel(component string, level slog.Level, ttl time.Duration) {
+	r.mu.Lock()
+	pending := r.reverts[component]
+	prev, hadPrev := r.setLocked(component, level)
+	if ttl > 0 {
+		rv := &levelRevert{level: prev, hadLevel: hadPrev}
+		if pending != nil {
+			rv.level, rv.hadLevel = pending.level, pending.hadLevel
+		}
+		rv.timer = time.AfterFunc(ttl, func() {
+			r.revert(component, rv)
+		})
+		r.reverts[component] = rv
+	}
+	r.mu.Unlock()
+
+	// Change is logged when the lock is released, since logging
+	// may need the registry to check the level.
+	slog.Info("log level changed",
+		slog.String(levelComponentKey, component),
+		slog.String("level", level.String()),
+		slog.String("previous_level", prev.String()),
+		slog.Duration("ttl", ttl),
+	)
+}
+
+// ResetLevel removes the component level, so records of the component are
+// filtered by the default level again.
+func (r *LevelRegistry) ResetLevel(component string) {
+	if component == "" {
+		return
+	}
+
+	r.mu.Lock()
+	r.stopRevertLocked(component)
+	delete(r.levels, component)
+	r.updateLocked()
+	r.mu.Unlock()
+
+	slog.Info("log level reset",
+		slog.String(levelComponentKey, component),
+	)
+}
+
+// set sets the level without logging the change.
+func (r *LevelRegistry) set(component string, level slog.Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setLocked(component, level)
+}
+
+func (r *LevelRegistry) revert(component string, rv *levelRevert) {
+	r.mu.Lock()
+	if r.reverts[component] != rv {
+		// The level was changed again after the timer fired.
+		r.mu.Unlock()
+		return
+	}
+	delete(r.reverts, component)
+	if rv.hadLevel {
+		r.levelVarLocked(component).Set(rv.level)
+	} else {
+		delete(r.levels, component)
+	}
+	r.updateLocked()
+	r.mu.Unlock()
+	level := r.level(component, true)
+
+	slog.Info("log level reverted",
+		slog.String(levelComponentKey, component),
+		slog.String("level", level.String()),
+	)
+}
+
+func (r *LevelRegistry) setLocked(component string, level slog.Level) (slog.Level, bool) {
+	r.stopRevertLocked(component)
+
+	prev, hadPrev := r.def.Level(), true
+	if component != "" {
+		var lv *slog.LevelVar
+		if lv, hadPrev = r.levels[component]; hadPrev {
+			prev = lv.Level()
+		}
+	}
+
+	r.levelVarLocked(component).Set(level)
+	r.updateLocked()
+
+	return prev, hadPrev
+}
+
+func (r *LevelRegistry) stopRevertLocked(component string) {
+	if rv, ok := r.reverts[component]; ok {
+		rv.timer.Stop()
+		delete(r.reverts, component)
+	}
+}
+
+func (r *LevelRegistry) levelVarLocked(component string) *slog.LevelVar {
+	if component == "" {
+		return r.def
+	}
+	lv, ok := r.levels[component]
+	if !ok {
+		lv = new(slog.LevelVar)
+		r.levels[component] = lv
+	}
+	return lv
+}
+
+// level returns the level of the longest configured prefix of the component.
+func (r *LevelRegistry) level(component string, ok bool) slog.Level {
+	table := *r.table.Load()
+	for ok && component != "" && len(table) > 0 {
+		if lv, found := table[component]; found {
+			return lv.Level()
+		}
+		component, ok = parentComponent(component)
+	}
+	return r.def.Level()
+}
+
+// updateLocked updates the minimal level and the table of component levels.
+func (r *LevelRegistry) updateLocked() {
+	var ret int64 = math.MaxInt64
+	table := make(map[string]*slog.LevelVar, len(r.levels))
+	for component, lv := range r.levels {
+		ret = min(ret, int64(lv.Level()))
+		table[component] = lv
+	}
+	r.minLevel.Store(ret)
+	r.table.Store(&table)
+}
+
+// LevelHandler returns the http.Handler reading and changing log levels of the registry.
+//
+//	GET                                       returns levels as JSON object
+//	PUT|POST ?level=debug[&component=db][&ttl=10m]  sets the level
+//	DELETE ?component=db                      removes the component level
+func LevelHandler(r *LevelRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			level, err := parseLevel(req.FormValue("level"))
+			if err != nil {
+				http.Error(w, "invalid level", http.StatusBadRequest)
+				return
+			}
+			var ttl time.Duration
+			if s := req.FormValue("ttl"); s != "" {
+				if ttl, err = time.ParseDuration(s); err != nil || ttl < 0 {
+					http.Error(w, "invalid ttl", http.StatusBadRequest)
+					return
+				}
+			}
+			r.SetLevel(req.FormValue("component"), level, ttl)
+		case http.MethodDelete:
+			r.ResetLevel(req.FormValue("component"))
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeLevels(w, r.Levels())
+	})
+}
+
+type levelsResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+func writeLevels(w http.ResponseWriter, levels map[string]slog.Level) {
+	resp := levelsResponse{
+		Level:      levels[""].String(),
+		Components: make(map[string]string, len(levels)),
+	}
+
+	for component, level := range levels {
+		if component != "" {
+			resp.Components[component] = level.String()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
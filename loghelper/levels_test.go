@@ -0,0 +1,87 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func levelRequest(t *testing.T, h http.Handler, method, query string) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/log/level?"+query, nil))
+
+	var resp map[string]any
+	if w.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestLevelHandler(t *testing.T) {
+
+	var (
+		buf    bytes.Buffer
+		levels = loghelper.NewLevelRegistry(slog.LevelInfo)
+		h      = loghelper.LevelHandler(levels)
+	)
+
+	logger, _ := loghelper.NewLogger(
+		loghelper.WithOutput(&buf),
+		loghelper.WithLevelRegistry(levels),
+	)
+	db := logger.With(loghelper.ComponentKey, "db")
+
+	db.Debug("db query")
+	assert.Empty(t, buf.String())
+
+	code, resp := levelRequest(t, h, http.MethodPut, "component=db&level=debug&ttl=50ms")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"level": "INFO", "components": map[string]any{"db": "DEBUG"}}, resp)
+
+	db.Debug("db query")
+	logger.Debug("other query")
+	assert.Contains(t, buf.String(), "db query")
+	assert.NotContains(t, buf.String(), "other query")
+
+	assert.Eventually(t, func() bool {
+		return levels.ComponentLevel("db") == slog.LevelInfo
+	}, time.Second, 10*time.Millisecond)
+
+	code, _ = levelRequest(t, h, http.MethodPost, "level=warn")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, slog.LevelWarn, levels.Default().Level())
+
+	code, resp = levelRequest(t, h, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"level": "WARN", "components": map[string]any{}}, resp)
+
+	code, _ = levelRequest(t, h, http.MethodPut, "level=loud")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSetLevelTTL(t *testing.T) {
+
+	levels := loghelper.NewLevelRegistry(slog.LevelInfo)
+	levels.SetLevel("db", slog.LevelWarn, 0)
+
+	// Consecutive temporary changes restore the level set before the first one.
+	levels.SetLevel("db", slog.LevelDebug, time.Hour)
+	levels.SetLevel("db", slog.LevelError, 50*time.Millisecond)
+	assert.Equal(t, slog.LevelError, levels.ComponentLevel("db"))
+	assert.Eventually(t, func() bool {
+		return levels.ComponentLevel("db") == slog.LevelWarn
+	}, time.Second, 10*time.Millisecond)
+
+	// The change without ttl cancels the pending restore.
+	levels.SetLevel("db", slog.LevelDebug, 20*time.Millisecond)
+	levels.SetLevel("db", slog.LevelError, 0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, levels.ComponentLevel("db"))
+}
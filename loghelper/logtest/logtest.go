@@ -0,0 +1,340 @@
+// Package logtest provides the slog.Handler recording log records in memory for tests.
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Record is the log record with resolved attributes. Attributes are keyed by
+// dot separated group path, e.g. "request.id", unnamed groups are inlined.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]any
+}
+
+// Attr returns the value of the attribute with the given key.
+func (r Record) Attr(key string) (any, bool) {
+	v, ok := r.Attrs[key]
+	return v, ok
+}
+
+// HasAttr reports whether the record has the attribute with the given key and value.
+// Values of non-comparable types like slices are compared deeply.
+func (r Record) HasAttr(key string, value any) bool {
+	v, ok := r.Attrs[key]
+	return ok && valuesEqual(slog.AnyValue(value), slog.AnyValue(v))
+}
+
+func valuesEqual(a, b slog.Value) bool {
+	if a.Kind() == slog.KindAny && b.Kind() == slog.KindAny {
+		return reflect.DeepEqual(a.Any(), b.Any())
+	}
+	return a.Equal(b)
+}
+
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %q", r.Level, r.Message)
+	for _, k := range sortedKeys(r.Attrs) {
+		fmt.Fprintf(&b, " %s=%v", k, r.Attrs[k])
+	}
+	return b.String()
+}
+
+// Handler is the slog.Handler recording log records in memory. It is safe for concurrent use.
+type Handler struct {
+	rec    *recorder
+	level  slog.Leveler
+	prefix string
+	attrs  map[string]any
+}
+
+// NewHandler returns the handler recording records at or above the level,
+// all records are recorded if the level is nil.
+func NewHandler(level slog.Leveler) *Handler {
+	if level == nil {
+		level = slog.Level(math.MinInt)
+	}
+	return &Handler{
+		rec:   &recorder{},
+		level: level,
+	}
+}
+
+// Capture returns the handler recording all records, which are
+// written to the test log if the test fails.
+func Capture(t testing.TB) *Handler {
+	t.Helper()
+
+	h := NewHandler(nil)
+	t.Cleanup(func() {
+		if t.Failed() {
+			h.Dump(t)
+		}
+	})
+	return h
+}
+
+// defaultOwners is the stack of names of tests holding the default logger.
+var defaultOwners struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	names []string
+}
+
+func init() {
+	defaultOwners.cond = sync.NewCond(&defaultOwners.mu)
+}
+
+// SetDefault installs the capturing handler as the default logger handler for the duration
+// of the test. Tests calling SetDefault are serialized, so parallel tests don't see each
+// other's records, the previous default logger is restored when the test finishes.
+// The test holding the default logger and its subtests may call SetDefault again.
+func SetDefault(t testing.TB) *Handler {
+	t.Helper()
+
+	acquireDefault(t.Name())
+	prev := slog.Default()
+
+	h := Capture(t)
+	slog.SetDefault(slog.New(h))
+
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		releaseDefault(t.Name())
+	})
+	return h
+}
+
+// acquireDefault waits until the default logger is free, or held by the test or its parent.
+func acquireDefault(name string) {
+	o := &defaultOwners
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(o.names) > 0 {
+		owner := o.names[len(o.names)-1]
+		if name == owner || strings.HasPrefix(name, owner+"/") {
+			break
+		}
+		o.cond.Wait()
+	}
+	o.names = append(o.names, name)
+}
+
+func releaseDefault(name string) {
+	o := &defaultOwners
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := len(o.names) - 1; i >= 0; i-- {
+		if o.names[i] == name {
+			o.names = append(o.names[:i], o.names[i+1:]...)
+			break
+		}
+	}
+	o.cond.Broadcast()
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	rec := Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]any, len(h.attrs)+r.NumAttrs()),
+	}
+	for k, v := range h.attrs {
+		rec.Attrs[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flatten(h.prefix, a, rec.Attrs)
+		return true
+	})
+
+	h.rec.add(rec)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	ret := *h
+	ret.attrs = make(map[string]any, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		ret.attrs[k] = v
+	}
+	for _, a := range attrs {
+		flatten(h.prefix, a, ret.attrs)
+	}
+	return &ret
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	ret := *h
+	ret.prefix = join(h.prefix, name)
+	return &ret
+}
+
+// Records returns all recorded records.
+func (h *Handler) Records() []Record {
+	return h.rec.filter(func(Record) bool { return true })
+}
+
+// Filter returns recorded records the function returns true for.
+func (h *Handler) Filter(f func(r Record) bool) []Record {
+	return h.rec.filter(f)
+}
+
+// ByLevel returns records of the given level.
+func (h *Handler) ByLevel(level slog.Level) []Record {
+	return h.Filter(func(r Record) bool { return r.Level == level })
+}
+
+// ByMessage returns records with the given message.
+func (h *Handler) ByMessage(msg string) []Record {
+	return h.Filter(func(r Record) bool { return r.Message == msg })
+}
+
+// ByAttr returns records having the attribute with the given key and value.
+func (h *Handler) ByAttr(key string, value any) []Record {
+	return h.Filter(func(r Record) bool { return r.HasAttr(key, value) })
+}
+
+// Reset removes all recorded records.
+func (h *Handler) Reset() {
+	h.rec.reset()
+}
+
+// Dump writes recorded records to the test log.
+func (h *Handler) Dump(t testing.TB) {
+	t.Helper()
+	for _, r := range h.Records() {
+		t.Log(r)
+	}
+}
+
+// AssertLogged asserts that the record with the given level and message, having all
+// the given attributes, specified as key-value pairs, was logged.
+func (h *Handler) AssertLogged(t testing.TB, level slog.Level, msg string, attrs ...any) bool {
+	t.Helper()
+
+	if len(h.Filter(matcher(level, msg, attrs))) < 1 {
+		t.Errorf("no %s record %q with attributes %v was logged, records:\n%s", level, msg, attrs, h.dump())
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged asserts that no record with the given level and message, having all
+// the given attributes, specified as key-value pairs, was logged.
+func (h *Handler) AssertNotLogged(t testing.TB, level slog.Level, msg string, attrs ...any) bool {
+	t.Helper()
+
+	if records := h.Filter(matcher(level, msg, attrs)); len(records) > 0 {
+		t.Errorf("unexpected %s record %q with attributes %v was logged: %v", level, msg, attrs, records)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) dump() string {
+	var lines []string
+	for _, r := range h.Records() {
+		lines = append(lines, "\t"+r.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func matcher(level slog.Level, msg string, attrs []any) func(r Record) bool {
+	return func(r Record) bool {
+		if r.Level != level || r.Message != msg {
+			return false
+		}
+		for i := 0; i+1 < len(attrs); i += 2 {
+			if key, ok := attrs[i].(string); !ok || !r.HasAttr(key, attrs[i+1]) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (r *recorder) add(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec)
+}
+
+func (r *recorder) filter(f func(r Record) bool) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ret []Record
+	for _, rec := range r.records {
+		if f(rec) {
+			ret = append(ret, rec)
+		}
+	}
+	return ret
+}
+
+func (r *recorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+func flatten(prefix string, a slog.Attr, m map[string]any) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = join(prefix, a.Key)
+		}
+		for _, ga := range v.Group() {
+			flatten(groupPrefix, ga, m)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	m[join(prefix, a.Key)] = v.Any()
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package logtest_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+	"github.com/vovanec/serror/loghelper/logtest"
+)
+
+func TestHandler(t *testing.T) {
+
+	h := logtest.Capture(t)
+	logger := slog.New(h).With("app", "test").WithGroup("request")
+
+	ctx := loghelper.Context(context.Background(), slog.String("trace_id", "abc"))
+	err := serror.New("user not found", slog.String("user_id", "1"))
+
+	logger.Info("request started", slog.String("id", "1"))
+	logger.Error("request failed", slog.String("id", "1"), loghelper.Attr(ctx, err))
+
+	records := h.Records()
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "request started", records[0].Message)
+		assert.Equal(t, map[string]any{"app": "test", "request.id": "1"}, records[0].Attrs)
+
+		v, ok := records[1].Attr("request.error.msg")
+		assert.True(t, ok)
+		assert.Equal(t, "user not found", v)
+		assert.True(t, records[1].HasAttr("request.trace_id", "abc"))
+		assert.False(t, records[1].HasAttr("request.trace_id", []string{"abc"}))
+	}
+
+	assert.Len(t, h.ByLevel(slog.LevelError), 1)
+	assert.Len(t, h.ByMessage("request started"), 1)
+	assert.Len(t, h.ByAttr("request.user_id", "1"), 1)
+
+	h.AssertLogged(t, slog.LevelError, "request failed", "request.id", "1", "app", "test")
+	h.AssertNotLogged(t, slog.LevelWarn, "request failed")
+
+	h.Reset()
+	assert.Empty(t, h.Records())
+}
+
+func TestSetDefault(t *testing.T) {
+	for _, name := range []string{"first", "second"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := logtest.SetDefault(t)
+			slog.Info("message", "test", name)
+
+			if assert.Len(t, h.Records(), 1) {
+				assert.True(t, h.Records()[0].HasAttr("test", name))
+			}
+		})
+	}
+}
+
+func TestSetDefaultNested(t *testing.T) {
+	parent := logtest.SetDefault(t)
+
+	for _, name := range []string{"first", "second"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			h := logtest.SetDefault(t)
+			slog.Info("message", "test", name)
+			h.AssertLogged(t, slog.LevelInfo, "message", "test", name)
+		})
+	}
+
+	slog.Info("message", "ids", []int{1, 2})
+	parent.AssertLogged(t, slog.LevelInfo, "message", "ids", []int{1, 2})
+	assert.Len(t, parent.Records(), 1)
+}
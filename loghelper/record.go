@@ -0,0 +1,175 @@
+package loghelper
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	"github.com/vovanec/serror"
+)
+
+const (
+	errKey         = "error"
+	errMsgKey      = "msg"
+	errStackKey    = "stack"
+	fingerprintKey = "fingerprint"
+	severityKey    = "severity"
+)
+
+// recordHasError reports whether the record carries an error either as an attribute
+// value or as an error group produced by logging a structured error with Attr.
+func recordHasError(r slog.Record) bool {
+	var found bool
+	recordAttrs(r, func(a slog.Attr) bool {
+		if _, ok := attrError(a); ok || isErrorGroup(a) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// recordErrorSeverity reports whether the record carries an error and returns the error
+// severity parsed from the "severity" log attribute of the error, or the record level
+// if the error has no valid severity.
+func recordErrorSeverity(r slog.Record) (slog.Level, bool) {
+	var (
+		hasErr                    bool
+		errSeverity, attrSeverity string
+	)
+	recordAttrs(r, func(a slog.Attr) bool {
+		if err, ok := attrError(a); ok {
+			hasErr = true
+			if v, ok := serror.LookupAttr(err, severityKey); ok {
+				errSeverity = v.String()
+			}
+		} else if isErrorGroup(a) {
+			// Attributes of the error logged with Attr are inlined into the record.
+			hasErr = true
+		} else if a.Key == severityKey {
+			attrSeverity = a.Value.Resolve().String()
+		}
+		return true
+	})
+
+	if !hasErr {
+		return 0, false
+	}
+	severity := errSeverity
+	if severity == "" {
+		severity = attrSeverity
+	}
+	if level, ok := parseSeverity(severity); ok {
+		return level, true
+	}
+	return r.Level, true
+}
+
+// parseSeverity parses the error severity as the log level, e.g. "warn", "ERROR+2"
+// or "critical", which is slog.LevelError+4.
+func parseSeverity(s string) (slog.Level, bool) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, false
+	case "warning":
+		return slog.LevelWarn, true
+	case "critical", "fatal", "panic":
+		return slog.LevelError + 4, true
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+// recordKey returns the key identifying the error carried by the record: the error
+// fingerprint if it is available, error message and origin otherwise. Records without
+// errors are identified by the log message and source location.
+func recordKey(r slog.Record) string {
+	var key string
+	recordAttrs(r, func(a slog.Attr) bool {
+		if err, ok := attrError(a); ok {
+			var fp serror.Fingerprinter
+			if errors.As(err, &fp) {
+				key = fp.Fingerprint()
+			} else {
+				key = err.Error()
+			}
+			return false
+		}
+		if isErrorGroup(a) {
+			var msg, stack string
+			for _, ga := range a.Value.Group() {
+				switch ga.Key {
+				case fingerprintKey:
+					key = ga.Value.String()
+					return false
+				case errMsgKey:
+					msg = ga.Value.String()
+				case errStackKey:
+					stack = ga.Value.String()
+				}
+			}
+			key = msg + "\x00" + stack
+			return false
+		}
+		return true
+	})
+
+	if key != "" {
+		return key
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s", r.Level, r.Message, recordSource(r))
+}
+
+// recordAttrs calls f on each record attribute, inlining unnamed groups
+// the same way slog handlers do. Iteration stops if f returns false.
+func recordAttrs(r slog.Record, f func(a slog.Attr) bool) {
+	r.Attrs(func(a slog.Attr) bool {
+		return inlineAttrs(a, f)
+	})
+}
+
+func inlineAttrs(a slog.Attr, f func(a slog.Attr) bool) bool {
+	if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			if !inlineAttrs(ga, f) {
+				return false
+			}
+		}
+		return true
+	}
+	return f(a)
+}
+
+func recordSource(r slog.Record) string {
+	if r.PC == 0 {
+		return ""
+	}
+	f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+func attrError(a slog.Attr) (error, bool) {
+	switch a.Value.Kind() {
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := a.Value.Any().(error)
+		return err, ok
+	}
+	return nil, false
+}
+
+func isErrorGroup(a slog.Attr) bool {
+	if a.Key != errKey || a.Value.Kind() != slog.KindGroup {
+		return false
+	}
+	for _, ga := range a.Value.Group() {
+		if ga.Key == errMsgKey {
+			return true
+		}
+	}
+	return false
+}
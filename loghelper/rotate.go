@@ -0,0 +1,380 @@
+package loghelper
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vovanec/serror"
+)
+
+const (
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+)
+
+type RotateOption func(c *rotateConfig)
+
+// WithMaxSize sets the size in bytes after which the file is rotated.
+func WithMaxSize(size int64) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxSize = size
+	}
+}
+
+// WithRotateInterval sets the interval after which the file is rotated.
+func WithRotateInterval(interval time.Duration) RotateOption {
+	return func(c *rotateConfig) {
+		c.interval = interval
+	}
+}
+
+// WithMaxBackups sets the number of rotated files to keep, all are kept by default.
+func WithMaxBackups(n int) RotateOption {
+	return func(c *rotateConfig) {
+		c.maxBackups = n
+	}
+}
+
+// WithCompress enables gzip compression of rotated files.
+func WithCompress(enabled bool) RotateOption {
+	return func(c *rotateConfig) {
+		c.compress = enabled
+	}
+}
+
+// WithRotateClock sets the function returning current time, mostly useful in tests.
+func WithRotateClock(now func() time.Time) RotateOption {
+	return func(c *rotateConfig) {
+		c.now = now
+	}
+}
+
+// WithFile sets default logger log output to the rotating file, which is opened on the
+// first write. The file is returned, so the caller can close it and reopen it on signals:
+//
+//	opt, f := loghelper.WithFile("/var/log/app.log", loghelper.WithMaxSize(100<<20))
+//	defer f.Close()
+//	defer f.ReopenOnSignal(syscall.SIGHUP)()
+//	loghelper.InitLogging(opt)
+func WithFile(path string, opts ...RotateOption) (LogOption, *RotatingFile) {
+	f := newRotatingFile(path, opts...)
+	return WithOutput(f), f
+}
+
+// RotatingFile is the io.WriteCloser writing to the file which is rotated when it
+// grows over the max size or when the rotation interval passes. Rotated files are renamed
+// to <path>.<timestamp>, with the -<n> suffix added if the name is taken, and optionally
+// compressed. Empty files are not rotated by interval. It is safe for concurrent use.
+type RotatingFile struct {
+	path string
+	conf rotateConfig
+
+	mu           sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+
+	// bgMu serializes compression and removal of rotated files.
+	bgMu sync.Mutex
+	wg   sync.WaitGroup
+}
+
+// NewRotatingFile opens the file for appending, creating it if necessary.
+func NewRotatingFile(path string, opts ...RotateOption) (*RotatingFile, error) {
+	f := newRotatingFile(path, opts...)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.openLocked(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func newRotatingFile(path string, opts ...RotateOption) *RotatingFile {
+	conf := rotateConfig{
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return &RotatingFile{
+		path: path,
+		conf: conf,
+	}
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.openLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.needsRotationLocked(len(p)) {
+		if err := f.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.openLocked(); err != nil {
+			return err
+		}
+	}
+	return f.rotateLocked()
+}
+
+// Reopen closes and reopens the file, which is useful when
+// the file was moved away by an external tool.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.closeLocked(); err != nil {
+		return err
+	}
+	return f.openLocked()
+}
+
+// ReopenOnSignal reopens the file every time one of the signals is received.
+// The returned function stops listening for signals.
+func (f *RotatingFile) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	var (
+		ch   = make(chan os.Signal, 1)
+		done = make(chan struct{})
+		once sync.Once
+	)
+
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				_ = f.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// Close closes the file and waits for compression of rotated files to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	err := f.closeLocked()
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
+
+func (f *RotatingFile) needsRotationLocked(n int) bool {
+	if f.conf.maxSize > 0 && f.size > 0 && f.size+int64(n) > f.conf.maxSize {
+		return true
+	}
+	if now := f.conf.now(); f.conf.interval > 0 && !now.Before(f.nextRotation) {
+		if f.size > 0 {
+			return true
+		}
+		// Empty files are not rotated, the interval starts over.
+		f.nextRotation = now.Add(f.conf.interval)
+	}
+	return false
+}
+
+func (f *RotatingFile) openLocked() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return serror.Wrap(err, "error creating log directory", "path", f.path)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return serror.Wrap(err, "error opening log file", "path", f.path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return serror.Wrap(err, "error opening log file", "path", f.path)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.nextRotation = f.conf.now().Add(f.conf.interval)
+
+	return nil
+}
+
+func (f *RotatingFile) closeLocked() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return serror.Wrap(err, "error closing log file", "path", f.path)
+	}
+	return nil
+}
+
+func (f *RotatingFile) rotateLocked() error {
+	if err := f.closeLocked(); err != nil {
+		return err
+	}
+
+	backup := f.backupName()
+	if err := os.Rename(f.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return serror.Wrap(err, "error rotating log file", "path", f.path, "backup", backup)
+	}
+
+	if err := f.openLocked(); err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		f.bgMu.Lock()
+		defer f.bgMu.Unlock()
+
+		if f.conf.compress {
+			_ = compressFile(backup)
+		}
+		if f.conf.maxBackups > 0 {
+			f.removeBackups()
+		}
+	}()
+
+	return nil
+}
+
+// backupName returns the name of the rotated file, which does not exist
+// either uncompressed or compressed.
+func (f *RotatingFile) backupName() string {
+	base := f.path + "." + f.conf.now().Format(backupTimeFormat)
+	backup := base
+	for seq := 1; fileExists(backup) || fileExists(backup+compressSuffix); seq++ {
+		backup = base + "-" + strconv.Itoa(seq)
+	}
+	return backup
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// removeBackups removes the oldest rotated files exceeding the max backups limit.
+func (f *RotatingFile) removeBackups() {
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		ts   string
+		seq  int
+	}
+
+	var backups []backup
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, f.path+"."), compressSuffix)
+		b := backup{path: m}
+		if i := strings.LastIndexByte(ts, '-'); i >= 0 {
+			var err error
+			if b.seq, err = strconv.Atoi(ts[i+1:]); err != nil {
+				continue
+			}
+			ts = ts[:i]
+		}
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			b.ts = ts
+			backups = append(backups, b)
+		}
+	}
+
+	if len(backups) <= f.conf.maxBackups {
+		return
+	}
+
+	// Timestamp format sorts lexicographically.
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].ts != backups[j].ts {
+			return backups[i].ts < backups[j].ts
+		}
+		return backups[i].seq < backups[j].seq
+	})
+	for _, b := range backups[:len(backups)-f.conf.maxBackups] {
+		_ = os.Remove(b.path)
+	}
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + compressSuffix)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+type rotateConfig struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	compress   bool
+	now        func() time.Time
+}
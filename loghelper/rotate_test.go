@@ -0,0 +1,132 @@
+package loghelper_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func TestRotatingFile(t *testing.T) {
+
+	var (
+		dir   = t.TempDir()
+		path  = filepath.Join(dir, "app.log")
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	)
+
+	f, err := loghelper.NewRotatingFile(path,
+		loghelper.WithMaxSize(10),
+		loghelper.WithRotateInterval(time.Hour),
+		loghelper.WithMaxBackups(2),
+		loghelper.WithCompress(true),
+		loghelper.WithRotateClock(clock.Now),
+	)
+	require.NoError(t, err)
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		clock.Advance(time.Second)
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	clock.Advance(time.Hour)
+	_, err = f.Write([]byte("fourth\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(data))
+
+	backups, err := filepath.Glob(path + ".*.gz")
+	require.NoError(t, err)
+	sort.Strings(backups)
+	if assert.Len(t, backups, 2) {
+		for i, want := range []string{"second\n", "third\n"} {
+			zr, err := os.Open(backups[i])
+			require.NoError(t, err)
+			r, err := gzip.NewReader(zr)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(data))
+			_ = zr.Close()
+		}
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "app.log")
+	)
+
+	f, err := loghelper.NewRotatingFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, os.Rename(path, path+".old"))
+	require.NoError(t, f.Reopen())
+
+	_, err = f.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(data))
+}
+
+func TestRotatingFileBackups(t *testing.T) {
+
+	var (
+		dir   = t.TempDir()
+		path  = filepath.Join(dir, "app.log")
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	)
+
+	opt, f := loghelper.WithFile(path,
+		loghelper.WithRotateInterval(time.Hour),
+		loghelper.WithMaxBackups(2),
+		loghelper.WithRotateClock(clock.Now),
+	)
+	logger, _ := loghelper.NewLogger(opt)
+
+	// Empty files are not rotated by interval.
+	clock.Advance(time.Hour)
+	logger.Info("first")
+
+	// Rotations within the same millisecond don't overwrite backups.
+	for _, msg := range []string{"second", "third", "fourth"} {
+		require.NoError(t, f.Rotate())
+		logger.Info(msg)
+	}
+	require.NoError(t, f.Close())
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	sort.Strings(backups)
+	if assert.Len(t, backups, 2) {
+		base := path + "." + clock.Now().Format("20060102T150405.000")
+		assert.Equal(t, []string{base + "-1", base + "-2"}, backups)
+
+		data, err := os.ReadFile(backups[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"msg":"third"`)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"fourth"`)
+}
@@ -0,0 +1,236 @@
+package loghelper
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingPolicy defines how records are sampled: the first First records per Interval
+// are passed, then every Thereafter-th record is passed and the rest are dropped.
+// If Key is set, records are counted separately per value of the log attribute
+// with that key, e.g. "route". Counters of at most 4096 recently seen values are
+// kept, the least recently used ones are reset when the limit is reached.
+type SamplingPolicy struct {
+	First      int
+	Thereafter int
+	Interval   time.Duration
+	Key        string
+}
+
+type SamplingOption func(c *samplingConfig)
+
+// WithSamplingPolicy sets the sampling policy for records of the given level.
+// Records of levels without a policy are not sampled.
+func WithSamplingPolicy(level slog.Level, p SamplingPolicy) SamplingOption {
+	return func(c *samplingConfig) {
+		c.policies[level] = p
+	}
+}
+
+// WithSamplingErrorLevel sets the severity starting from which records carrying errors are
+// never dropped. The severity is parsed from the "severity" log attribute of the error, e.g.
+// "warn" or "critical", the record level is used if the error has none. Default is slog.LevelError.
+func WithSamplingErrorLevel(level slog.Level) SamplingOption {
+	return func(c *samplingConfig) {
+		c.errorLevel = level
+	}
+}
+
+// WithSamplingClock sets the function returning current time, mostly useful in tests.
+func WithSamplingClock(now func() time.Time) SamplingOption {
+	return func(c *samplingConfig) {
+		c.now = now
+	}
+}
+
+// SamplingStats contains sampling handler counters.
+type SamplingStats struct {
+	Passed  uint64
+	Dropped uint64
+}
+
+// SamplingHandler is the slog.Handler middleware which samples records according
+// to per-level policies. Records carrying errors with the severity at or above the error
+// level are always passed.
+type SamplingHandler struct {
+	next   slog.Handler
+	state  *samplingState
+	attrs  map[string]slog.Value
+	groups bool
+}
+
+// NewSamplingHandler returns the sampling handler passing sampled records to the next handler.
+func NewSamplingHandler(next slog.Handler, opts ...SamplingOption) *SamplingHandler {
+
+	conf := samplingConfig{
+		policies:   make(map[slog.Level]SamplingPolicy),
+		errorLevel: slog.LevelError,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return &SamplingHandler{
+		next: next,
+		state: &samplingState{
+			conf:     conf,
+			counters: make(map[samplingKey]*list.Element),
+			lru:      list.New(),
+		},
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+
+	p, ok := h.state.conf.policies[r.Level]
+	if !ok {
+		h.state.passed.Add(1)
+		return h.next.Handle(ctx, r)
+	}
+	if sev, hasErr := recordErrorSeverity(r); hasErr && sev >= h.state.conf.errorLevel {
+		h.state.passed.Add(1)
+		return h.next.Handle(ctx, r)
+	}
+
+	key := samplingKey{level: r.Level}
+	if p.Key != "" {
+		key.value = h.attrValue(r, p.Key)
+	}
+
+	if !h.state.sample(key, p) {
+		h.state.dropped.Add(1)
+		return nil
+	}
+
+	h.state.passed.Add(1)
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	ret := &SamplingHandler{
+		next:   h.next.WithAttrs(attrs),
+		state:  h.state,
+		attrs:  h.attrs,
+		groups: h.groups,
+	}
+	if !h.groups {
+		// Only top-level attributes can be used as sampling keys.
+		ret.attrs = make(map[string]slog.Value, len(h.attrs)+len(attrs))
+		for k, v := range h.attrs {
+			ret.attrs[k] = v
+		}
+		for _, a := range attrs {
+			inlineAttrs(a, func(a slog.Attr) bool {
+				ret.attrs[a.Key] = a.Value
+				return true
+			})
+		}
+	}
+	return ret
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{
+		next:   h.next.WithGroup(name),
+		state:  h.state,
+		attrs:  h.attrs,
+		groups: h.groups || name != "",
+	}
+}
+
+// Stats returns the sampling counters.
+func (h *SamplingHandler) Stats() SamplingStats {
+	return SamplingStats{
+		Passed:  h.state.passed.Load(),
+		Dropped: h.state.dropped.Load(),
+	}
+}
+
+func (h *SamplingHandler) attrValue(r slog.Record, key string) string {
+	var (
+		ret   string
+		found bool
+	)
+	recordAttrs(r, func(a slog.Attr) bool {
+		if a.Key == key {
+			ret, found = a.Value.Resolve().String(), true
+		}
+		return !found
+	})
+	if !found {
+		if v, ok := h.attrs[key]; ok {
+			ret = v.Resolve().String()
+		}
+	}
+	return ret
+}
+
+type samplingConfig struct {
+	policies   map[slog.Level]SamplingPolicy
+	errorLevel slog.Level
+	now        func() time.Time
+}
+
+type samplingKey struct {
+	level slog.Level
+	value string
+}
+
+type samplingCounter struct {
+	key   samplingKey
+	start time.Time
+	n     int
+}
+
+// maxSamplingCounters is the maximal number of counters, the least recently used
+// counters are evicted when it is reached.
+const maxSamplingCounters = 4096
+
+type samplingState struct {
+	mu       sync.Mutex
+	conf     samplingConfig
+	counters map[samplingKey]*list.Element
+	lru      *list.List
+	passed   atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+func (s *samplingState) sample(key samplingKey, p SamplingPolicy) bool {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.conf.now()
+
+	var c *samplingCounter
+	if el, ok := s.counters[key]; ok {
+		s.lru.MoveToFront(el)
+		c = el.Value.(*samplingCounter)
+		if p.Interval > 0 && now.Sub(c.start) >= p.Interval {
+			c.start, c.n = now, 0
+		}
+	} else {
+		if s.lru.Len() >= maxSamplingCounters {
+			evicted := s.lru.Remove(s.lru.Back()).(*samplingCounter)
+			delete(s.counters, evicted.key)
+		}
+		c = &samplingCounter{key: key, start: now}
+		s.counters[key] = s.lru.PushFront(c)
+	}
+
+	c.n++
+	if c.n <= p.First {
+		return true
+	}
+	return p.Thereafter > 0 && (c.n-p.First)%p.Thereafter == 0
+}
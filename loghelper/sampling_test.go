@@ -0,0 +1,102 @@
+package loghelper_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+func TestSamplingHandler(t *testing.T) {
+
+	var (
+		buf   bytes.Buffer
+		clock = &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		h     = loghelper.NewSamplingHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithSamplingClock(clock.Now),
+			loghelper.WithSamplingPolicy(slog.LevelInfo, loghelper.SamplingPolicy{
+				First:      2,
+				Thereafter: 3,
+				Interval:   time.Second,
+				Key:        "route",
+			}),
+		)
+		logger = slog.New(h)
+	)
+
+	for i := 0; i < 8; i++ {
+		logger.Info("request", "route", "/a")
+		logger.With("route", "/b").Info("request")
+		logger.Error("request failed", loghelper.Attr(serror.New("failure", "i", i)))
+	}
+
+	// first 2, then 5th and 8th records per route, all errors
+	assert.Len(t, decodeLines(t, &buf), 2*4+8)
+	assert.Equal(t, loghelper.SamplingStats{Passed: 16, Dropped: 8}, h.Stats())
+
+	clock.Advance(time.Second)
+	buf.Reset()
+	logger.Info("request", "route", "/a")
+	assert.Len(t, decodeLines(t, &buf), 1)
+}
+
+func TestSamplingHandlerSeverity(t *testing.T) {
+
+	var (
+		buf bytes.Buffer
+		h   = loghelper.NewSamplingHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithSamplingErrorLevel(slog.LevelWarn),
+			loghelper.WithSamplingPolicy(slog.LevelInfo, loghelper.SamplingPolicy{First: 1}),
+		)
+		logger = slog.New(h)
+	)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("retrying", loghelper.Attr(serror.New("failure", "severity", "critical")))
+		logger.Info("retrying", "error", serror.New("failure", "severity", "warning"))
+		logger.Info("retrying", loghelper.Attr(serror.New("failure", "severity", "info")))
+		logger.Info("retrying", loghelper.Attr(serror.New("failure", "i", i)))
+	}
+	assert.Equal(t, loghelper.SamplingStats{Passed: 7, Dropped: 5}, h.Stats())
+
+	// Records at levels without a policy are passed without looking at their attributes.
+	var resolved int
+	logger.Warn("retrying", "severity", valuerFunc(func() slog.Value {
+		resolved++
+		return slog.StringValue("warn")
+	}))
+	assert.Equal(t, 1, resolved)
+}
+
+type valuerFunc func() slog.Value
+
+func (f valuerFunc) LogValue() slog.Value { return f() }
+
+func TestSamplingHandlerKeyLimit(t *testing.T) {
+
+	var (
+		buf bytes.Buffer
+		h   = loghelper.NewSamplingHandler(
+			slog.NewJSONHandler(&buf, nil),
+			loghelper.WithSamplingPolicy(slog.LevelInfo, loghelper.SamplingPolicy{First: 1, Key: "id"}),
+		)
+		logger = slog.New(h)
+	)
+
+	logger.Info("request", "id", -1)
+	logger.Info("request", "id", -1)
+	assert.Equal(t, loghelper.SamplingStats{Passed: 1, Dropped: 1}, h.Stats())
+
+	// The counter of the least recently used key is evicted.
+	for i := 0; i < 4096; i++ {
+		logger.Info("request", "id", i)
+	}
+	logger.Info("request", "id", -1)
+	assert.Equal(t, loghelper.SamplingStats{Passed: 4098, Dropped: 1}, h.Stats())
+}
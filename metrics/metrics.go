@@ -0,0 +1,212 @@
+// Package metrics counts structured errors by code, severity and origin function,
+// and exposes counters via expvar and in the Prometheus text format:
+//
+//	c := metrics.New()
+//	defer c.Start()()
+//	c.Publish("errors")
+//	http.Handle("/metrics", c.Handler())
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vovanec/serror"
+)
+
+const (
+	severityKey = "severity"
+
+	// MetricName is the name of the Prometheus counter of errors.
+	MetricName = "serror_errors_total"
+	// OverflowValue is the value of all labels of errors counted after
+	// the number of series reached the limit.
+	OverflowValue = "other"
+	// UnknownValue is the value of code and function labels of errors without them,
+	// like errors created by serror.New without log args.
+	UnknownValue = "unknown"
+
+	defaultSeverity = "error"
+)
+
+// Labels identify the series of errors.
+type Labels struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Function string `json:"function"`
+}
+
+// Sample is the number of errors with the labels.
+type Sample struct {
+	Labels
+	Count uint64 `json:"count"`
+}
+
+type Option func(c *config)
+
+// WithMaxSeries sets the maximal number of distinct label sets, 1000 by default.
+// Errors with new labels are counted with all labels set to OverflowValue when
+// the limit is reached.
+func WithMaxSeries(n int) Option {
+	return func(c *config) {
+		c.maxSeries = n
+	}
+}
+
+// WithSeverity sets the function returning the error severity. By default, it is
+// the value of the "severity" log attribute of the error, or "error" if there is none.
+func WithSeverity(f func(err error) string) Option {
+	return func(c *config) {
+		c.severity = f
+	}
+}
+
+type config struct {
+	maxSeries int
+	severity  func(err error) string
+}
+
+// Collector counts errors.
+type Collector struct {
+	conf config
+
+	mu     sync.Mutex
+	series map[Labels]uint64
+}
+
+// New returns the collector, Start starts counting errors.
+func New(opts ...Option) *Collector {
+	conf := config{
+		maxSeries: 1000,
+		severity:  attrSeverity,
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return &Collector{
+		conf:   conf,
+		series: make(map[Labels]uint64),
+	}
+}
+
+// Start registers the collector as the serror observer, so errors returned by serror.New,
+// serror.Wrap and their variants are counted. The returned function stops counting.
+func (c *Collector) Start() (stop func()) {
+	return serror.AddObserver(c.Observe)
+}
+
+// Observe counts the error, unless it wraps the structured error, which was
+// already counted when it was created, so every failure is counted once.
+func (c *Collector) Observe(_ context.Context, err error) {
+	if err == nil {
+		return
+	}
+	var inner serror.ErrorOrigin
+	if errors.As(errors.Unwrap(err), &inner) {
+		return
+	}
+
+	labels := Labels{
+		Code:     serror.Code(err),
+		Severity: c.conf.severity(err),
+	}
+	var origin serror.ErrorOrigin
+	if errors.As(err, &origin) {
+		labels.Function = origin.Origin().Function
+	}
+	if labels.Code == "" {
+		labels.Code = UnknownValue
+	}
+	if labels.Function == "" {
+		labels.Function = UnknownValue
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.series[labels]; !ok && len(c.series) >= c.conf.maxSeries {
+		labels = Labels{Code: OverflowValue, Severity: OverflowValue, Function: OverflowValue}
+	}
+	c.series[labels]++
+}
+
+// Snapshot returns current counters sorted by labels.
+func (c *Collector) Snapshot() []Sample {
+	c.mu.Lock()
+	ret := make([]Sample, 0, len(c.series))
+	for l, n := range c.series {
+		ret = append(ret, Sample{Labels: l, Count: n})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		a, b := ret[i].Labels, ret[j].Labels
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Severity != b.Severity {
+			return a.Severity < b.Severity
+		}
+		return a.Function < b.Function
+	})
+	return ret
+}
+
+// Reset sets all counters to zero.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.series = make(map[Labels]uint64)
+}
+
+// Publish publishes the counters snapshot as the expvar variable with the name.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return c.Snapshot()
+	}))
+}
+
+// Handler returns the http.Handler serving counters in the Prometheus text format.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = c.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes counters in the Prometheus text format.
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# HELP %s Number of errors created by code, severity and origin function.\n", MetricName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", MetricName)
+	for _, s := range c.Snapshot() {
+		fmt.Fprintf(&b, "%s{code=\"%s\",severity=\"%s\",function=\"%s\"} %d\n", MetricName,
+			escapeLabel(s.Code), escapeLabel(s.Severity), escapeLabel(s.Function), s.Count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+// attrSeverity returns the value of the "severity" log attribute of the error.
+func attrSeverity(err error) string {
+	if v, ok := serror.LookupAttr(err, severityKey); ok {
+		return v.String()
+	}
+	return defaultSeverity
+}
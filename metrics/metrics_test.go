@@ -0,0 +1,83 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/metrics"
+)
+
+func getUser(id int) error {
+	return serror.New("user not found", slog.Int("id", id), slog.String("code", "not_found"), slog.String("severity", "warn"))
+}
+
+func TestCollector(t *testing.T) {
+	c := metrics.New()
+	stop := c.Start()
+
+	for i := 0; i < 3; i++ {
+		// Wrapping errors are not counted again.
+		_ = serror.Wrap(getUser(i), "error handling request", slog.Int("attempt", i))
+	}
+	_ = serror.Wrap(io.EOF, "error reading", slog.String("code", "io"))
+	_ = serror.New("plain")
+
+	stop()
+	_ = getUser(4)
+
+	assert.Equal(t, []metrics.Sample{
+		{Labels: metrics.Labels{Code: "io", Severity: "error", Function: "github.com/vovanec/serror/metrics_test.TestCollector"}, Count: 1},
+		{Labels: metrics.Labels{Code: "not_found", Severity: "warn", Function: "github.com/vovanec/serror/metrics_test.getUser"}, Count: 3},
+		{Labels: metrics.Labels{Code: "unknown", Severity: "error", Function: "unknown"}, Count: 1},
+	}, c.Snapshot())
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP serror_errors_total Number of errors created by code, severity and origin function.
+# TYPE serror_errors_total counter
+serror_errors_total{code="io",severity="error",function="github.com/vovanec/serror/metrics_test.TestCollector"} 1
+serror_errors_total{code="not_found",severity="warn",function="github.com/vovanec/serror/metrics_test.getUser"} 3
+serror_errors_total{code="unknown",severity="error",function="unknown"} 1
+`, rec.Body.String())
+
+	c.Publish("test_errors")
+	var published []metrics.Sample
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("test_errors").String()), &published))
+	assert.Equal(t, c.Snapshot(), published)
+
+	c.Reset()
+	assert.Empty(t, c.Snapshot())
+}
+
+func TestMaxSeries(t *testing.T) {
+	c := metrics.New(
+		metrics.WithMaxSeries(2),
+		metrics.WithSeverity(func(err error) string { return "critical" }),
+	)
+
+	for _, code := range []string{"a", "b", "c", "a", "d", `"quoted"`} {
+		c.Observe(nil, serror.New("error", slog.String("code", code)))
+	}
+
+	assert.Equal(t, []metrics.Sample{
+		{Labels: metrics.Labels{Code: "a", Severity: "critical", Function: "github.com/vovanec/serror/metrics_test.TestMaxSeries"}, Count: 2},
+		{Labels: metrics.Labels{Code: "b", Severity: "critical", Function: "github.com/vovanec/serror/metrics_test.TestMaxSeries"}, Count: 1},
+		{Labels: metrics.Labels{Code: "other", Severity: "other", Function: "other"}, Count: 3},
+	}, c.Snapshot())
+
+	c = metrics.New()
+	c.Observe(nil, serror.New("error", slog.String("code", "a\"b\\c\nd")))
+
+	var buf strings.Builder
+	require.NoError(t, c.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), `{code="a\"b\\c\nd",`)
+}
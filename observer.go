@@ -0,0 +1,43 @@
+package serror
+
+import (
+	"context"
+)
+
+// Observer is called for every error returned by New, Wrap and their variants.
+// ctx is the first context passed among log args, or nil if there is none.
+// Observers are called synchronously, so they must be fast and must not block.
+type Observer func(ctx context.Context, err error)
+
+var observers hookList[Observer]
+
+// AddObserver registers the observer, the returned function removes it.
+func AddObserver(o Observer) (remove func()) {
+	return observers.add(o)
+}
+
+// observe calls observers with the error and the context, or
+// the first context among args if the context is nil.
+func observe(ctx context.Context, args []any, err error) error {
+	list := observers.load()
+	if len(list) == 0 {
+		return err
+	}
+	if ctx == nil {
+		ctx = contextOf(args)
+	}
+	for _, o := range list {
+		(*o)(ctx, err)
+	}
+	return err
+}
+
+// contextOf returns the first context among log args, or nil if there is none.
+func contextOf(args []any) context.Context {
+	for _, arg := range args {
+		if ctx, ok := arg.(context.Context); ok {
+			return ctx
+		}
+	}
+	return nil
+}
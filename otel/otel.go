@@ -0,0 +1,191 @@
+// Package otel integrates structured errors and logs with OpenTelemetry tracing.
+// NewHandler adds IDs of the active trace and span to log records, and RecordErrors
+// records errors created with a context on the active span:
+//
+//	defer otel.RecordErrors()()
+//	slog.SetDefault(slog.New(otel.NewHandler(handler)))
+//
+//	// The error is recorded on the span active in ctx.
+//	err := serror.Wrap(err, "error getting user", ctx)
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/vovanec/serror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDKey is the log attribute key of the trace ID.
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the log attribute key of the span ID.
+	SpanIDKey = "span_id"
+
+	stackTraceKey = "exception.stacktrace"
+	errStackKey   = "error.stack"
+	errMsgKey     = "error.msg"
+)
+
+// Handler is the slog.Handler middleware adding IDs of the span
+// active in the record context to log records.
+type Handler struct {
+	next slog.Handler
+}
+
+// NewHandler returns the handler passing records to the next handler.
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r = r.Clone()
+			r.AddAttrs(
+				slog.String(TraceIDKey, sc.TraceID().String()),
+				slog.String(SpanIDKey, sc.SpanID().String()),
+			)
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
+
+type RecordOption func(c *recordConfig)
+
+// WithStatus sets the status of the span the error is recorded on to error.
+// The status is left to the caller by default, since not every error fails the operation.
+func WithStatus() RecordOption {
+	return func(c *recordConfig) {
+		c.setStatus = true
+	}
+}
+
+type recordConfig struct {
+	setStatus bool
+}
+
+// RecordErrors starts recording errors returned by serror.New, serror.Wrap and
+// their variants called with a context on the span active in the context. The
+// exception event carries error log attributes and the error stack. Errors wrapping
+// structured errors are not recorded, so every failure is recorded once, where it
+// was created. The returned function stops recording.
+func RecordErrors(opts ...RecordOption) (stop func()) {
+	var conf recordConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	return serror.AddObserver(conf.recordError)
+}
+
+func (c recordConfig) recordError(ctx context.Context, err error) {
+	if ctx == nil {
+		return
+	}
+	var inner serror.ErrorOrigin
+	if errors.As(errors.Unwrap(err), &inner) {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	span.RecordError(err, trace.WithAttributes(errorAttributes(err)...))
+	if c.setStatus {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// errorAttributes returns flattened error log attributes, the error
+// stack is converted to the exception.stacktrace attribute.
+func errorAttributes(err error) []attribute.KeyValue {
+	lv, ok := err.(slog.LogValuer)
+	if !ok {
+		return nil
+	}
+
+	var ret []attribute.KeyValue
+	flatten("", slog.Any("", lv.LogValue()), func(key string, v slog.Value) {
+		switch key {
+		case errMsgKey, errStackKey:
+			// The message is recorded as exception.message, the stack is added below.
+		default:
+			ret = append(ret, attributeOf(key, v))
+		}
+	})
+	if st, ok := err.(serror.StackTracer); ok && len(st.StackTrace()) > 0 {
+		ret = append(ret, attribute.String(stackTraceKey, stackTrace(st.StackTrace())))
+	}
+	return ret
+}
+
+// stackTrace formats origins one function and location per origin,
+// like goroutine stacks, the innermost origin first.
+func stackTrace(st serror.StackTrace) string {
+	var b strings.Builder
+	for _, o := range st {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", o.Function, o.File, o.Line)
+	}
+	return b.String()
+}
+
+func flatten(prefix string, a slog.Attr, f func(key string, v slog.Value)) {
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + a.Key
+	}
+	if a.Key == "" {
+		key = prefix
+	}
+
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			flatten(key, ga, f)
+		}
+		return
+	}
+	f(key, v)
+}
+
+func attributeOf(key string, v slog.Value) attribute.KeyValue {
+	switch v.Kind() {
+	case slog.KindBool:
+		return attribute.Bool(key, v.Bool())
+	case slog.KindInt64:
+		return attribute.Int64(key, v.Int64())
+	case slog.KindUint64:
+		return attribute.Int64(key, int64(v.Uint64()))
+	case slog.KindFloat64:
+		return attribute.Float64(key, v.Float64())
+	case slog.KindString:
+		return attribute.String(key, v.String())
+	case slog.KindAny:
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			return attribute.String(key, s.String())
+		}
+	}
+	return attribute.String(key, v.String())
+}
@@ -0,0 +1,101 @@
+package otel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror"
+	serrorotel "github.com/vovanec/serror/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracer(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp, exporter
+}
+
+func TestHandler(t *testing.T) {
+	tp, _ := newTracer(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(serrorotel.NewHandler(slog.NewJSONHandler(&buf, nil))).With("a", 1)
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "operation")
+	logger.InfoContext(ctx, "in span")
+	span.End()
+	logger.InfoContext(context.Background(), "no span")
+
+	dec := json.NewDecoder(&buf)
+
+	var rec map[string]any
+	require.NoError(t, dec.Decode(&rec))
+	assert.Equal(t, span.SpanContext().TraceID().String(), rec[serrorotel.TraceIDKey])
+	assert.Equal(t, span.SpanContext().SpanID().String(), rec[serrorotel.SpanIDKey])
+	assert.EqualValues(t, 1, rec["a"])
+
+	rec = nil
+	require.NoError(t, dec.Decode(&rec))
+	assert.NotContains(t, rec, serrorotel.TraceIDKey)
+}
+
+func TestRecordErrors(t *testing.T) {
+	tp, exporter := newTracer(t)
+
+	stop := serrorotel.RecordErrors()
+	defer stop()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "operation")
+	err := serror.New("user not found", ctx, slog.Int("user_id", 42), slog.Group("db", slog.String("table", "users")))
+	_ = serror.New("no context", slog.Int("user_id", 42))
+	_ = serror.Wrap(err, "error handling request", ctx)
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	require.Len(t, spans[0].Events, 1)
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans[0].Events[0].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+	assert.Equal(t, "user not found", attrs["exception.message"].AsString())
+	assert.Equal(t, int64(42), attrs["user_id"].AsInt64())
+	assert.Equal(t, "users", attrs["db.table"].AsString())
+	assert.Regexp(t, `^github.com/vovanec/serror/otel_test.TestRecordErrors\n\t/.*otel_test.go:\d+$`,
+		attrs["exception.stacktrace"].AsString())
+	assert.NotContains(t, attrs, attribute.Key("error.msg"))
+	assert.Error(t, err)
+
+	stop()
+	exporter.Reset()
+
+	ctx, span = tp.Tracer("test").Start(context.Background(), "operation")
+	_ = serror.Wrap(errors.New("error"), "not recorded", ctx)
+	span.End()
+	require.Len(t, exporter.GetSpans(), 1)
+	assert.Empty(t, exporter.GetSpans()[0].Events)
+	exporter.Reset()
+
+	defer serrorotel.RecordErrors(serrorotel.WithStatus())()
+
+	ctx, span = tp.Tracer("test").Start(context.Background(), "operation")
+	_ = serror.New("user not found", ctx, slog.Int("user_id", 42))
+	span.End()
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "user not found", spans[0].Status.Description)
+}
@@ -0,0 +1,268 @@
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/internal"
+)
+
+const (
+	errKey      = "error"
+	codeKey     = "code"
+	severityKey = "severity"
+
+	platform = "go"
+)
+
+// Event is the error event in the Sentry event format.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Message     string            `json:"message,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Exception   *ExceptionList    `json:"exception,omitempty"`
+}
+
+// ExceptionList is the error chain, the innermost error first.
+type ExceptionList struct {
+	Values []Exception `json:"values"`
+}
+
+// Exception is the error in the chain.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace is the error stack, the outermost frame first.
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is the stack frame.
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// newEvent converts the error to the event. Log attributes of the error and the context
+// become extra data, attributes with tag keys become tags.
+func newEvent(ctx context.Context, err error, tagKeys []string) *Event {
+	e := &Event{
+		EventID:     eventID(),
+		Timestamp:   time.Now().UTC(),
+		Platform:    platform,
+		Level:       level(err),
+		Fingerprint: []string{serror.Fingerprint(err)},
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]any),
+		Exception:   &ExceptionList{Values: exceptions(err)},
+	}
+
+	if lv, ok := logValue(err); ok {
+		for _, a := range lv.Group() {
+			if a.Key != errKey {
+				e.Extra[a.Key] = jsonValue(a.Value)
+			}
+		}
+	}
+	if ctx != nil {
+		// Attributes of the error take precedence over attributes of the context.
+		for _, a := range internal.LogAttrsFromContext(ctx) {
+			if _, ok := e.Extra[a.Key]; !ok {
+				e.Extra[a.Key] = jsonValue(a.Value)
+			}
+		}
+	}
+
+	for _, k := range tagKeys {
+		if v, ok := e.Extra[k]; ok && isScalar(v) {
+			e.Tags[k] = fmt.Sprint(v)
+			delete(e.Extra, k)
+		}
+	}
+	return e
+}
+
+// exceptions returns structured errors of the chain and the innermost error. The stack
+// of the outermost structured error contains frames of all structured errors further
+// down the chain, so it is attached to the innermost structured error only.
+func exceptions(err error) []Exception {
+	var (
+		ret   []Exception
+		stack *Stacktrace
+		last  = -1
+	)
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		_, structured := e.(serror.ErrorOrigin)
+		if !structured && errors.Unwrap(e) != nil {
+			continue
+		}
+		if structured {
+			if stack == nil {
+				stack = stacktrace(e)
+			}
+			last = len(ret)
+		}
+		ret = append(ret, Exception{
+			Type:  errorType(e),
+			Value: e.Error(),
+		})
+	}
+	if last >= 0 {
+		ret[last].Stacktrace = stack
+	}
+
+	// The innermost error goes first.
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// errorType returns the error code, or the type name of the unstructured error.
+func errorType(err error) string {
+	if _, ok := err.(serror.ErrorOrigin); ok {
+		if code := serror.Code(err); code != "" {
+			return code
+		}
+		return errKey
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+func stacktrace(err error) *Stacktrace {
+	st, ok := err.(serror.StackTracer)
+	if !ok || len(st.StackTrace()) < 1 {
+		return nil
+	}
+
+	// The error stack starts at the origin, while frames start at the outermost caller.
+	stack := st.StackTrace()
+	frames := make([]Frame, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		frames = append(frames, frame(stack[i]))
+	}
+	return &Stacktrace{Frames: frames}
+}
+
+func frame(o serror.Origin) Frame {
+	module, function := splitFunction(o.Function)
+	return Frame{
+		Function: function,
+		Module:   module,
+		Filename: shortPath(o.File),
+		AbsPath:  o.File,
+		Lineno:   o.Line,
+		InApp:    !strings.Contains(o.File, "/pkg/mod/") && !strings.HasPrefix(module, "runtime"),
+	}
+}
+
+// splitFunction splits the qualified function name, e.g. "github.com/a/b.(*T).M",
+// into the package path and the function name.
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	dot += slash + 1
+	return name[:dot], name[dot+1:]
+}
+
+// shortPath returns the file name with its directory.
+func shortPath(file string) string {
+	i := strings.LastIndex(file, "/")
+	if i < 0 {
+		return file
+	}
+	if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+		return file[j+1:]
+	}
+	return file
+}
+
+// level maps the "severity" log attribute to the event level.
+func level(err error) string {
+	v, ok := serror.LookupAttr(err, severityKey)
+	if !ok {
+		return "error"
+	}
+	switch s := strings.ToLower(v.String()); s {
+	case "debug", "info", "error", "fatal":
+		return s
+	case "warn", "warning":
+		return "warning"
+	case "critical", "panic":
+		return "fatal"
+	default:
+		return "error"
+	}
+}
+
+func logValue(err error) (slog.Value, bool) {
+	var lv slog.LogValuer
+	if !errors.As(err, &lv) {
+		return slog.Value{}, false
+	}
+	v := lv.LogValue().Resolve()
+	return v, v.Kind() == slog.KindGroup
+}
+
+// jsonValue converts the log value to the JSON-friendly form.
+func jsonValue(v slog.Value) any {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		m := make(map[string]any)
+		for _, a := range v.Group() {
+			m[a.Key] = jsonValue(a.Value)
+		}
+		return m
+	case slog.KindDuration, slog.KindTime:
+		return v.String()
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return x.Error()
+		case fmt.Stringer:
+			return x.String()
+		}
+	}
+	return v.Any()
+}
+
+func isScalar(v any) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return false
+	}
+	return true
+}
+
+func eventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,394 @@
+// Package report sends structured errors to error trackers as events in the Sentry event
+// format. Errors are converted to events with the error chain, stack frames, log attributes
+// of the error and the context, and the fingerprint, and sent in batches in the background:
+//
+//	sink, err := report.NewSentrySink(os.Getenv("SENTRY_DSN"))
+//	...
+//	r := report.New(sink, report.WithRelease(version), report.WithRateLimit(10, 100))
+//	defer r.Close(context.Background())
+//	...
+//	r.Report(ctx, err)
+package report
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reporter reports errors.
+type Reporter interface {
+	// Report reports the error, log attributes of the context are reported with it.
+	Report(ctx context.Context, err error)
+	// Flush waits until errors reported before the call are sent or the context is done.
+	Flush(ctx context.Context) error
+}
+
+type Option func(c *config)
+
+// WithTags sets keys of log attributes reported as event tags rather than extra data,
+// "code" and "severity" by default. Only attributes with scalar values become tags.
+func WithTags(keys ...string) Option {
+	return func(c *config) {
+		c.tags = keys
+	}
+}
+
+// WithRelease sets the release of reported events.
+func WithRelease(release string) Option {
+	return func(c *config) {
+		c.release = release
+	}
+}
+
+// WithEnvironment sets the environment of reported events, e.g. "production".
+func WithEnvironment(env string) Option {
+	return func(c *config) {
+		c.environment = env
+	}
+}
+
+// WithServerName sets the server name of reported events, the hostname by default.
+func WithServerName(name string) Option {
+	return func(c *config) {
+		c.serverName = name
+	}
+}
+
+// WithBeforeSend sets the function called with every event and the error it was converted
+// from before the event is queued. It may modify the event, e.g. remove sensitive data,
+// or return nil to drop it.
+func WithBeforeSend(f func(e *Event, err error) *Event) Option {
+	return func(c *config) {
+		c.beforeSend = f
+	}
+}
+
+// WithRateLimit limits the number of reported events to rate per second on average
+// with bursts of up to burst events. Events over the limit are dropped before they are
+// converted and passed to the WithBeforeSend function. Events are not limited by default.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(c *config) {
+		c.rate = rate
+		c.burst = burst
+	}
+}
+
+// WithBatchSize sets the maximal number of events sent at once, 10 by default.
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithFlushInterval sets how often queued events are sent if the batch is not full, 1s by default.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.flushInterval = d
+	}
+}
+
+// WithQueueSize sets the number of events the queue can hold, 100 by default.
+// Events reported when the queue is full are dropped.
+func WithQueueSize(size int) Option {
+	return func(c *config) {
+		c.queueSize = size
+	}
+}
+
+// WithLogger sets the logger of errors sending events, the default logger by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+type config struct {
+	tags          []string
+	release       string
+	environment   string
+	serverName    string
+	beforeSend    func(e *Event, err error) *Event
+	rate          float64
+	burst         int
+	batchSize     int
+	flushInterval time.Duration
+	queueSize     int
+	logger        *slog.Logger
+}
+
+// Client is the Reporter sending events to the sink in batches in a background
+// goroutine. Close must be called to send queued events on shutdown.
+type Client struct {
+	conf    config
+	sink    Sink
+	limiter *limiter
+
+	// mu guards closed, the lock is not held while sending to the queue,
+	// senders are tracked instead, so Close is not blocked by a full queue.
+	mu      sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup
+
+	queue   chan item
+	closing chan struct{}
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	// ctx is the context of sending events, it is canceled when Close gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type item struct {
+	event   *Event
+	flushed chan struct{}
+}
+
+// New returns the client sending events to the sink and starts its background goroutine.
+func New(sink Sink, opts ...Option) *Client {
+
+	conf := config{
+		tags:          []string{codeKey, severityKey},
+		batchSize:     10,
+		flushInterval: time.Second,
+		queueSize:     100,
+	}
+	conf.serverName, _ = os.Hostname()
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	c := &Client{
+		conf:    conf,
+		sink:    sink,
+		queue:   make(chan item, conf.queueSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	if conf.rate > 0 {
+		c.limiter = newLimiter(conf.rate, conf.burst)
+	}
+	go c.run()
+
+	return c
+}
+
+// Report converts the error to the event and queues it. Nil errors are ignored.
+func (c *Client) Report(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	if !c.limiter.allow() {
+		c.dropped.Add(1)
+		return
+	}
+
+	e := newEvent(ctx, err, c.conf.tags)
+	e.Release = c.conf.release
+	e.Environment = c.conf.environment
+	e.ServerName = c.conf.serverName
+
+	if c.conf.beforeSend != nil {
+		if e = c.conf.beforeSend(e, err); e == nil {
+			return
+		}
+	}
+
+	if !c.enter() {
+		c.dropped.Add(1)
+		return
+	}
+	defer c.senders.Done()
+
+	select {
+	case c.queue <- item{event: e}:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because of the rate limit,
+// the full queue or because they were reported after Close.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Flush waits until events queued before the call are sent or the context is done.
+func (c *Client) Flush(ctx context.Context) error {
+	if !c.enter() {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case c.queue <- item{flushed: flushed}:
+		c.senders.Done()
+	case <-c.closing:
+		// Close sends all queued events.
+		c.senders.Done()
+		return nil
+	case <-ctx.Done():
+		c.senders.Done()
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events and waits until queued events are sent or the context
+// is done, in which case sending is canceled.
+func (c *Client) Close(ctx context.Context) error {
+
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.closing)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		return ctx.Err()
+	}
+}
+
+// enter registers the sender and reports whether the client is not closed,
+// the sender must call senders.Done when it is done with the queue.
+func (c *Client) enter() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+	c.senders.Add(1)
+	return true
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	defer c.cancel()
+
+	ticker := time.NewTicker(c.conf.flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Event
+	send := func() {
+		if len(batch) > 0 {
+			c.send(batch)
+			batch = nil
+		}
+	}
+	handle := func(it item) {
+		if it.flushed != nil {
+			send()
+			close(it.flushed)
+			return
+		}
+		batch = append(batch, it.event)
+		if len(batch) >= c.conf.batchSize {
+			send()
+		}
+	}
+
+	for {
+		select {
+		case it := <-c.queue:
+			handle(it)
+		case <-ticker.C:
+			send()
+		case <-c.closing:
+			// Events are queued until senders which started before Close are done,
+			// then the rest of the queue is sent.
+			sendersDone := make(chan struct{})
+			go func() {
+				c.senders.Wait()
+				close(sendersDone)
+			}()
+			for {
+				select {
+				case it := <-c.queue:
+					handle(it)
+				case <-sendersDone:
+					for {
+						select {
+						case it := <-c.queue:
+							handle(it)
+						default:
+							send()
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *Client) send(events []*Event) {
+	if err := c.sink.Send(c.ctx, events); err != nil {
+		logger := c.conf.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("error sending error events", slog.Int("events", len(events)), slog.Any("error", err))
+	}
+}
+
+// limiter is the token bucket rate limiter.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow reports whether the event may be sent, the nil limiter allows all events.
+func (l *limiter) allow() bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,263 @@
+package report_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+	"github.com/vovanec/serror/report"
+	"github.com/vovanec/serror/report/reporttest"
+)
+
+func loadUser(id string) error {
+	return serror.Wrap(fs.ErrNotExist, "error reading user file",
+		slog.String("code", "not_found"),
+		slog.String("user_id", id),
+	)
+}
+
+func handleRequest(id string) error {
+	return serror.Wrap(loadUser(id), "error handling request",
+		slog.String("severity", "warn"),
+		slog.Group("http", slog.String("method", "GET")),
+	)
+}
+
+// recordingSink records batches of sent events.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]*report.Event
+}
+
+func (s *recordingSink) Send(_ context.Context, events []*report.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingSink) events() []*report.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ret []*report.Event
+	for _, b := range s.batches {
+		ret = append(ret, b...)
+	}
+	return ret
+}
+
+func TestReport(t *testing.T) {
+	var buf bytes.Buffer
+	c := report.New(report.NewWriterSink(&buf),
+		report.WithRelease("v1.2.3"),
+		report.WithEnvironment("test"),
+		report.WithServerName("host"),
+	)
+
+	ctx := loghelper.Context(context.Background(), slog.String("request_id", "42"), slog.String("user_id", "ctx"))
+	err := handleRequest("7")
+	c.Report(ctx, err)
+	c.Report(ctx, nil)
+	require.NoError(t, c.Close(context.Background()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var e report.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &e))
+
+	assert.Len(t, e.EventID, 32)
+	assert.False(t, e.Timestamp.IsZero())
+	assert.Equal(t, "go", e.Platform)
+	assert.Equal(t, "warning", e.Level)
+	assert.Equal(t, "v1.2.3", e.Release)
+	assert.Equal(t, "test", e.Environment)
+	assert.Equal(t, "host", e.ServerName)
+	assert.Equal(t, []string{serror.Fingerprint(err)}, e.Fingerprint)
+	assert.Equal(t, map[string]string{"code": "not_found", "severity": "warn"}, e.Tags)
+	assert.Equal(t, map[string]any{
+		"user_id":    "7",
+		"request_id": "42",
+		"http":       map[string]any{"method": "GET"},
+	}, e.Extra)
+
+	require.NotNil(t, e.Exception)
+	ex := e.Exception.Values
+	require.Len(t, ex, 3)
+
+	assert.Equal(t, "*errors.errorString", ex[0].Type)
+	assert.Equal(t, "file does not exist", ex[0].Value)
+	assert.Nil(t, ex[0].Stacktrace)
+
+	assert.Equal(t, "not_found", ex[1].Type)
+	assert.Equal(t, "error reading user file: file does not exist", ex[1].Value)
+	require.NotNil(t, ex[1].Stacktrace)
+	frames := ex[1].Stacktrace.Frames
+	require.Len(t, frames, 2)
+	assert.Equal(t, "handleRequest", frames[0].Function)
+	assert.Equal(t, 33, frames[0].Lineno)
+
+	f := frames[1]
+	assert.Equal(t, "loadUser", f.Function)
+	assert.Equal(t, "github.com/vovanec/serror/report_test", f.Module)
+	assert.Equal(t, "report/report_test.go", f.Filename)
+	assert.True(t, strings.HasSuffix(f.AbsPath, "/report/report_test.go"))
+	assert.Equal(t, 26, f.Lineno)
+	assert.True(t, f.InApp)
+
+	assert.Equal(t, "not_found", ex[2].Type)
+	assert.Equal(t, "error handling request: error reading user file: file does not exist", ex[2].Value)
+	assert.Nil(t, ex[2].Stacktrace)
+
+	c.Report(ctx, err)
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestReportBatches(t *testing.T) {
+	sink := &recordingSink{}
+	c := report.New(sink, report.WithBatchSize(2), report.WithTags("user_id"))
+
+	for _, id := range []string{"1", "2", "3"} {
+		c.Report(context.Background(), loadUser(id))
+	}
+	require.NoError(t, c.Flush(context.Background()))
+
+	sink.mu.Lock()
+	assert.Len(t, sink.batches, 2)
+	assert.Len(t, sink.batches[0], 2)
+	assert.Len(t, sink.batches[1], 1)
+	sink.mu.Unlock()
+
+	events := sink.events()
+	assert.Equal(t, map[string]string{"user_id": "3"}, events[2].Tags)
+	assert.Equal(t, "not_found", events[2].Extra["code"])
+
+	c.Report(context.Background(), errors.New("plain error"))
+	require.NoError(t, c.Close(context.Background()))
+
+	events = sink.events()
+	require.Len(t, events, 4)
+	assert.Equal(t, "error", events[3].Level)
+	assert.Empty(t, events[3].Tags)
+	require.Len(t, events[3].Exception.Values, 1)
+	assert.Equal(t, "*errors.errorString", events[3].Exception.Values[0].Type)
+}
+
+func TestReportFilters(t *testing.T) {
+	sink := &recordingSink{}
+	c := report.New(sink,
+		report.WithRateLimit(0.001, 2),
+		report.WithBeforeSend(func(e *report.Event, err error) *report.Event {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			delete(e.Extra, "user_id")
+			return e
+		}),
+	)
+
+	c.Report(context.Background(), serror.Wrap(context.Canceled, "error waiting", slog.Int("attempt", 1)))
+	for _, id := range []string{"1", "2", "3"} {
+		c.Report(context.Background(), loadUser(id))
+	}
+	require.NoError(t, c.Close(context.Background()))
+
+	// Events dropped by the WithBeforeSend function count towards the rate limit.
+	events := sink.events()
+	require.Len(t, events, 1)
+	assert.Empty(t, events[0].Extra)
+	assert.Equal(t, uint64(2), c.Dropped())
+}
+
+// hungSink blocks sending events until the context is canceled.
+type hungSink struct {
+	started  chan struct{}
+	canceled chan error
+}
+
+func (s *hungSink) Send(ctx context.Context, _ []*report.Event) error {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	s.canceled <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestReportHungSink(t *testing.T) {
+	sink := &hungSink{started: make(chan struct{}, 2), canceled: make(chan error, 2)}
+	c := report.New(sink,
+		report.WithBatchSize(1),
+		report.WithQueueSize(1),
+		report.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	c.Report(context.Background(), loadUser("1"))
+	<-sink.started
+	c.Report(context.Background(), loadUser("2"))
+
+	// Flush blocks on the full queue without blocking Close and Report.
+	flushed := make(chan error, 1)
+	go func() { flushed <- c.Flush(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Close(ctx), context.DeadlineExceeded)
+
+	reported := make(chan struct{})
+	go func() {
+		c.Report(context.Background(), loadUser("3"))
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("Report is blocked")
+	}
+
+	// Sending is canceled when Close gives up waiting.
+	assert.ErrorIs(t, <-sink.canceled, context.Canceled)
+	assert.NoError(t, <-flushed)
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestSentrySink(t *testing.T) {
+	srv := reporttest.NewServer()
+	defer srv.Close()
+
+	sink, err := report.NewSentrySink(srv.DSN())
+	require.NoError(t, err)
+
+	c := report.New(sink)
+	c.Report(context.Background(), loadUser("1"))
+	c.Report(context.Background(), loadUser("2"))
+	require.NoError(t, c.Flush(context.Background()))
+
+	events := srv.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "2", events[1].Extra["user_id"])
+	assert.Equal(t, events[0].Fingerprint, events[1].Fingerprint)
+
+	srv.SetStatus(http.StatusTooManyRequests)
+	err = sink.Send(context.Background(), []*report.Event{&events[0]})
+	assert.ErrorContains(t, err, "429 Too Many Requests")
+	require.NoError(t, c.Close(context.Background()))
+
+	_, err = report.NewSentrySink("https://host/1")
+	assert.Error(t, err)
+	_, err = report.NewSentrySink("https://key@host")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+// Package reporttest provides the HTTP server receiving error events for tests.
+package reporttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/vovanec/serror/report"
+)
+
+const (
+	// Key is the key of the server DSN.
+	Key = "public"
+	// Project is the project ID of the server DSN.
+	Project = "1"
+)
+
+// Server is the HTTP server implementing the Sentry store endpoint, which records
+// received events in memory. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []report.Event
+	status int
+}
+
+// NewServer starts and returns the server, Close must be called to stop it.
+func NewServer() *Server {
+	s := &Server{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// DSN returns the DSN of the server to pass to report.NewSentrySink.
+func (s *Server) DSN() string {
+	return strings.Replace(s.URL, "://", "://"+Key+"@", 1) + "/" + Project
+}
+
+// Events returns received events.
+func (s *Server) Events() []report.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]report.Event(nil), s.events...)
+}
+
+// SetStatus sets the HTTP status the server responds with, events are not
+// recorded if the status is not 200 OK.
+func (s *Server) SetStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/api/"+Project+"/store/" {
+		http.NotFound(w, r)
+		return
+	}
+	if !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key="+Key) {
+		http.Error(w, "invalid auth", http.StatusUnauthorized)
+		return
+	}
+
+	var e report.Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	status := s.status
+	if status == http.StatusOK {
+		s.events = append(s.events, e)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": e.EventID})
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sentryClient = "serror/1.0"
+
+	// defaultHTTPTimeout limits the time of sending events by the default HTTP client.
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// Sink sends events.
+type Sink interface {
+	Send(ctx context.Context, events []*Event) error
+}
+
+// SinkFunc is the function implementing the Sink interface.
+type SinkFunc func(ctx context.Context, events []*Event) error
+
+func (f SinkFunc) Send(ctx context.Context, events []*Event) error {
+	return f(ctx, events)
+}
+
+// WriterSink writes events to the writer, e.g. the file, as JSON lines.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns the sink writing events to the writer.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Send(_ context.Context, events []*Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+type HTTPOption func(c *httpConfig)
+
+// WithHTTPClient sets the HTTP client sending events, by default the client with 10 seconds timeout.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(c *httpConfig) {
+		c.client = client
+	}
+}
+
+// WithHeader adds the header to requests sending events.
+func WithHeader(key, value string) HTTPOption {
+	return func(c *httpConfig) {
+		c.header.Add(key, value)
+	}
+}
+
+type httpConfig struct {
+	client *http.Client
+	header http.Header
+}
+
+// HTTPSink posts every event as JSON to the URL.
+type HTTPSink struct {
+	conf httpConfig
+	url  string
+}
+
+// NewHTTPSink returns the sink posting events to the URL.
+func NewHTTPSink(url string, opts ...HTTPOption) *HTTPSink {
+
+	conf := httpConfig{
+		client: &http.Client{Timeout: defaultHTTPTimeout},
+		header: make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return &HTTPSink{
+		conf: conf,
+		url:  url,
+	}
+}
+
+// NewSentrySink returns the sink posting events to the store endpoint of
+// the Sentry project identified by the DSN, e.g. "https://key@host/42".
+func NewSentrySink(dsn string, opts ...HTTPOption) (*HTTPSink, error) {
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+
+	key := u.User.Username()
+	prefix, project := "", strings.TrimPrefix(u.Path, "/")
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	if key == "" || project == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DSN %q: key, host and project ID are required", u.Redacted())
+	}
+
+	store := fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project)
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, key)
+	return NewHTTPSink(store, append([]HTTPOption{WithHeader("X-Sentry-Auth", auth)}, opts...)...), nil
+}
+
+func (s *HTTPSink) Send(ctx context.Context, events []*Event) error {
+	var errs []error
+	for _, e := range events {
+		if err := s.post(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("error sending event %s: %w", e.EventID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *HTTPSink) post(ctx context.Context, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.conf.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.conf.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
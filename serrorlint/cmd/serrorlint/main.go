@@ -0,0 +1,20 @@
+// Command serrorlint checks for misuse of serror and loghelper packages.
+//
+// It can be run standalone:
+//
+//	serrorlint ./...
+//
+// or as a go vet tool:
+//
+//	go vet -vettool=$(which serrorlint) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/vovanec/serror/serrorlint"
+)
+
+func main() {
+	singlechecker.Main(serrorlint.Analyzer)
+}
@@ -0,0 +1,386 @@
+// Package serrorlint defines the analyzer detecting misuse of serror and loghelper packages:
+//
+//   - log arguments of serror constructors, like serror.New and serror.Wrap, context helpers,
+//     loghelper.Attr and loghelper.Context which produce !BADKEY attributes at runtime;
+//   - errors which are logged and then returned, so they are logged twice;
+//   - errors formatted by fmt.Errorf with %v or %s verbs, which drops log attributes;
+//   - serror.New and serror.Wrap results which are not used and serror.Wrap(nil, ...) calls.
+package serrorlint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	serrorPath    = "github.com/vovanec/serror"
+	loghelperPath = "github.com/vovanec/serror/loghelper"
+	slogPath      = "log/slog"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "serrorlint",
+	Doc:      "check for misuse of serror and loghelper packages",
+	URL:      "https://pkg.go.dev/github.com/vovanec/serror/serrorlint",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// logArgsFuncs maps functions accepting log args to the index of the first log arg.
+var logArgsFuncs = map[string]int{
+	serrorPath + ".New":         1,
+	serrorPath + ".Wrap":        2,
+	serrorPath + ".NewDepth":    2,
+	serrorPath + ".Newt":        1,
+	serrorPath + ".NewtDepth":   2,
+	serrorPath + ".NewCtx":      2,
+	serrorPath + ".WrapCtx":     3,
+	serrorPath + ".FromContext": 1,
+	serrorPath + ".WithTimeout": 3,
+	loghelperPath + ".Attr":     0,
+	loghelperPath + ".Context":  1,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+		(*ast.ExprStmt)(nil),
+		(*ast.BlockStmt)(nil),
+		(*ast.CaseClause)(nil),
+		(*ast.CommClause)(nil),
+	}
+
+	insp.Preorder(nodeFilter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			checkLogArgs(pass, n)
+			checkErrorf(pass, n)
+			checkWrapNil(pass, n)
+		case *ast.ExprStmt:
+			checkUnused(pass, n)
+		case *ast.BlockStmt:
+			checkDoubleLogging(pass, n.List)
+		case *ast.CaseClause:
+			checkDoubleLogging(pass, n.Body)
+		case *ast.CommClause:
+			checkDoubleLogging(pass, n.Body)
+		}
+	})
+
+	return nil, nil
+}
+
+func funcName(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+		return ""
+	}
+	return fn.Pkg().Path() + "." + fn.Name()
+}
+
+func shortName(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// checkLogArgs reports log args which can't be converted to log attributes
+// the same way the serror internal package does it at runtime.
+func checkLogArgs(pass *analysis.Pass, call *ast.CallExpr) {
+	name := funcName(pass, call)
+	first, ok := logArgsFuncs[name]
+	if !ok || call.Ellipsis.IsValid() || len(call.Args) <= first {
+		return
+	}
+
+	args := call.Args[first:]
+	for len(args) > 0 {
+		arg := args[0]
+		t := pass.TypesInfo.TypeOf(arg)
+		switch {
+		case t == nil:
+			return
+		case isString(t):
+			if len(args) == 1 {
+				pass.Report(analysis.Diagnostic{
+					Pos:     arg.Pos(),
+					End:     arg.End(),
+					Message: fmt.Sprintf("%s call has a key without a value, it will be logged as !BADKEY", shortName(name)),
+				})
+				return
+			}
+			args = args[2:]
+			continue
+		case isNamed(t, slogPath, "Attr"), implements(pass, t, "context", "Context"),
+			implements(pass, t, "", "error"), implements(pass, t, slogPath, "LogValuer"):
+		default:
+			pass.Report(analysis.Diagnostic{
+				Pos: arg.Pos(),
+				End: arg.End(),
+				Message: fmt.Sprintf("%s call has an argument of type %s which is not a key, slog.Attr, "+
+					"context.Context, error or slog.LogValuer, it will be logged as !BADKEY",
+					shortName(name), types.TypeString(t, types.RelativeTo(pass.Pkg))),
+			})
+		}
+		args = args[1:]
+	}
+}
+
+// checkErrorf reports errors formatted by fmt.Errorf with %v or %s verbs.
+func checkErrorf(pass *analysis.Pass, call *ast.CallExpr) {
+	if funcName(pass, call) != "fmt.Errorf" || len(call.Args) < 2 || call.Ellipsis.IsValid() {
+		return
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return
+	}
+	tv := pass.TypesInfo.Types[lit]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+
+	verbs, ok := formatVerbs(lit.Value)
+	if !ok {
+		return
+	}
+
+	for i, v := range verbs {
+		if i+1 >= len(call.Args) {
+			return
+		}
+		arg := call.Args[i+1]
+		if t := pass.TypesInfo.TypeOf(arg); t == nil || !implements(pass, t, "", "error") || (v.verb != 'v' && v.verb != 's') {
+			continue
+		}
+
+		pass.Report(analysis.Diagnostic{
+			Pos: arg.Pos(),
+			End: arg.End(),
+			Message: fmt.Sprintf("fmt.Errorf formats error with %%%c, error chain and log attributes are lost, use %%w",
+				v.verb),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message: "Use %w verb",
+				TextEdits: []analysis.TextEdit{{
+					Pos:     lit.Pos() + token.Pos(v.offset),
+					End:     lit.Pos() + token.Pos(v.offset+v.len),
+					NewText: []byte("%w"),
+				}},
+			}},
+		})
+	}
+}
+
+type formatVerb struct {
+	verb   rune
+	offset int
+	len    int
+}
+
+// formatVerbs returns verbs of the quoted format string with their offsets in the literal.
+// Formats with explicit argument indexes or * width are not supported.
+func formatVerbs(lit string) ([]formatVerb, bool) {
+	if _, err := strconv.Unquote(lit); err != nil || strings.Contains(lit, "\\") {
+		// Escape sequences shift offsets, such literals are skipped.
+		return nil, false
+	}
+
+	var verbs []formatVerb
+	for i := 1; i < len(lit)-1; i++ {
+		if lit[i] != '%' {
+			continue
+		}
+		start := i
+		for i++; i < len(lit)-1 && strings.IndexByte("+-# 0123456789.", lit[i]) >= 0; i++ {
+		}
+		if i >= len(lit)-1 {
+			return verbs, true
+		}
+		switch c := lit[i]; c {
+		case '%':
+			continue
+		case '[', '*':
+			return nil, false
+		default:
+			verbs = append(verbs, formatVerb{verb: rune(c), offset: start, len: i - start + 1})
+		}
+	}
+	return verbs, true
+}
+
+// checkWrapNil reports serror.Wrap calls with nil error, which always return nil.
+func checkWrapNil(pass *analysis.Pass, call *ast.CallExpr) {
+	if funcName(pass, call) != serrorPath+".Wrap" || len(call.Args) < 1 {
+		return
+	}
+	if tv, ok := pass.TypesInfo.Types[call.Args[0]]; ok && tv.IsNil() {
+		pass.Reportf(call.Pos(), "serror.Wrap is called with nil error and always returns nil")
+	}
+}
+
+// checkUnused reports serror.New and serror.Wrap calls whose result is discarded.
+func checkUnused(pass *analysis.Pass, stmt *ast.ExprStmt) {
+	call, ok := astutil.Unparen(stmt.X).(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	if name := funcName(pass, call); name == serrorPath+".New" || name == serrorPath+".Wrap" {
+		pass.Reportf(call.Pos(), "result of %s call is not used", shortName(name))
+	}
+}
+
+// checkDoubleLogging reports errors which are logged and then returned from the same block.
+func checkDoubleLogging(pass *analysis.Pass, stmts []ast.Stmt) {
+	for i, stmt := range stmts {
+		es, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := astutil.Unparen(es.X).(*ast.CallExpr)
+		if !ok || !isLogCall(pass, call) {
+			continue
+		}
+
+		logged := loggedErrors(pass, call)
+		if len(logged) < 1 {
+			continue
+		}
+
+		for _, next := range stmts[i+1:] {
+			ret, ok := next.(*ast.ReturnStmt)
+			if !ok {
+				continue
+			}
+			if obj := returnedError(pass, ret, logged); obj != nil {
+				pass.Report(analysis.Diagnostic{
+					Pos: call.Pos(),
+					End: call.End(),
+					Message: fmt.Sprintf("error %s is logged and returned, it will be logged twice; "+
+						"return it with log attributes instead", obj.Name()),
+				})
+			}
+			break
+		}
+	}
+}
+
+var logFuncs = map[string]bool{
+	"Debug": true, "Info": true, "Warn": true, "Error": true,
+	"DebugContext": true, "InfoContext": true, "WarnContext": true, "ErrorContext": true,
+	"Log": true, "LogAttrs": true,
+}
+
+func isLogCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != slogPath || !logFuncs[fn.Name()] {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil {
+		return true
+	}
+	recv := sig.Recv().Type()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	return isNamed(recv, slogPath, "Logger")
+}
+
+// loggedErrors returns error variables passed to the log call, directly or through other calls.
+func loggedErrors(pass *analysis.Pass, call *ast.CallExpr) map[types.Object]bool {
+	ret := make(map[types.Object]bool)
+	for _, arg := range call.Args {
+		ast.Inspect(arg, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if obj, ok := pass.TypesInfo.Uses[id].(*types.Var); ok && implements(pass, obj.Type(), "", "error") {
+					ret[obj] = true
+				}
+			}
+			return true
+		})
+	}
+	return ret
+}
+
+func returnedError(pass *analysis.Pass, ret *ast.ReturnStmt, logged map[types.Object]bool) types.Object {
+	var found types.Object
+	for _, res := range ret.Results {
+		ast.Inspect(res, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && found == nil {
+				if obj := pass.TypesInfo.Uses[id]; obj != nil && logged[obj] {
+					found = obj
+				}
+			}
+			return found == nil
+		})
+	}
+	return found
+}
+
+// isString reports whether the type is string, values of named string types
+// are not keys at runtime.
+func isString(t types.Type) bool {
+	return t == types.Typ[types.String] || t == types.Typ[types.UntypedString]
+}
+
+// isNamed reports whether the type is exactly the named type, pointers to it don't match.
+func isNamed(t types.Type, pkg, name string) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Name() == name && obj.Pkg() != nil && obj.Pkg().Path() == pkg
+}
+
+// implements reports whether the type implements the named interface, the empty
+// package path denotes the universe scope.
+func implements(pass *analysis.Pass, t types.Type, pkg, name string) bool {
+	scope := types.Universe
+	if pkg != "" {
+		p := findPackage(pass.Pkg, pkg, make(map[*types.Package]bool))
+		if p == nil {
+			return false
+		}
+		scope = p.Scope()
+	}
+
+	obj := scope.Lookup(name)
+	if obj == nil {
+		return false
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	return ok && types.Implements(t, iface)
+}
+
+// findPackage finds the package among direct and indirect imports of the package.
+func findPackage(pkg *types.Package, path string, seen map[*types.Package]bool) *types.Package {
+	if pkg.Path() == path {
+		return pkg
+	}
+	seen[pkg] = true
+	for _, imp := range pkg.Imports() {
+		if seen[imp] {
+			continue
+		}
+		if p := findPackage(imp, path, seen); p != nil {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package serrorlint_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/vovanec/serror/serrorlint"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), serrorlint.Analyzer, "a")
+}
@@ -0,0 +1,71 @@
+package a
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+type version struct{}
+
+type key string
+
+func (version) LogValue() slog.Value {
+	return slog.StringValue("1.0")
+}
+
+func logArgs(ctx context.Context, err error) error {
+	_ = serror.New("error", "key", "value", slog.Int("a", 1), ctx, err, version{})
+	_ = serror.New("error", "key")               // want `New call has a key without a value, it will be logged as !BADKEY`
+	_ = serror.Wrap(err, "error", 42)            // want `Wrap call has an argument of type int which is not a key`
+	_ = loghelper.Attr(ctx, "a", 1, "b")         // want `Attr call has a key without a value`
+	ctx = loghelper.Context(ctx, 1.5, "a", "b")  // want `Context call has an argument of type float64`
+	_ = serror.New("error", key("key"), "value") // want `New call has an argument of type key which is not a key`
+	_ = serror.NewDepth(1, "error", "key")       // want `NewDepth call has a key without a value`
+	_ = serror.Newt("{key} error", "key")        // want `Newt call has a key without a value`
+	_ = serror.NewtDepth(1, "{a} error", 1)      // want `NewtDepth call has an argument of type int`
+	_ = serror.NewCtx(ctx, "error", "key")       // want `NewCtx call has a key without a value`
+	_ = serror.WrapCtx(ctx, err, "error", 1)     // want `WrapCtx call has an argument of type int`
+	_ = serror.FromContext(ctx, "key")           // want `FromContext call has a key without a value`
+
+	ctx, cancel := serror.WithTimeout(ctx, time.Second, "timeout", "key") // want `WithTimeout call has a key without a value`
+	defer cancel()
+	attr := slog.Int("a", 1)
+	_ = serror.New("error", &attr) // want `New call has an argument of type \*log/slog.Attr which is not a key`
+
+	args := []any{"key"}
+	return serror.New("error", args...)
+}
+
+func errorf(err error) error {
+	if err != nil {
+		return fmt.Errorf("error: %v", err) // want `fmt.Errorf formats error with %v, error chain and log attributes are lost, use %w`
+	}
+	return fmt.Errorf("error %d: %w, %s", 1, err, "text")
+}
+
+func unused(err error) error {
+	serror.Wrap(err, "error")        // want `result of serror.Wrap call is not used`
+	serror.New("error")              // want `result of serror.New call is not used`
+	return serror.Wrap(nil, "error") // want `serror.Wrap is called with nil error and always returns nil`
+}
+
+func doubleLogging(logger *slog.Logger, err error) error {
+	if err != nil {
+		slog.Error("error occurred", loghelper.Attr(err)) // want `error err is logged and returned, it will be logged twice`
+		return serror.Wrap(err, "error")
+	}
+
+	switch {
+	case err != nil:
+		logger.Warn("error occurred", "error", err) // want `error err is logged and returned`
+		return err
+	}
+
+	slog.Error("error occurred", "error", err)
+	return nil
+}
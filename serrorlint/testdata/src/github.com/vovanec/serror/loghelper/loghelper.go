@@ -0,0 +1,14 @@
+package loghelper
+
+import (
+	"context"
+	"log/slog"
+)
+
+func Attr(args ...any) slog.Attr {
+	return slog.Attr{}
+}
+
+func Context(ctx context.Context, args ...any) context.Context {
+	return ctx
+}
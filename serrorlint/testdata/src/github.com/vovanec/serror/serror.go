@@ -0,0 +1,42 @@
+package serror
+
+import (
+	"context"
+	"time"
+)
+
+func New(message string, args ...any) error {
+	return nil
+}
+
+func Wrap(err error, message string, args ...any) error {
+	return nil
+}
+
+func NewDepth(depth int, message string, args ...any) error {
+	return nil
+}
+
+func Newt(template string, args ...any) error {
+	return nil
+}
+
+func NewtDepth(depth int, template string, args ...any) error {
+	return nil
+}
+
+func NewCtx(ctx context.Context, message string, args ...any) error {
+	return nil
+}
+
+func WrapCtx(ctx context.Context, err error, message string, args ...any) error {
+	return nil
+}
+
+func FromContext(ctx context.Context, args ...any) error {
+	return nil
+}
+
+func WithTimeout(parent context.Context, timeout time.Duration, message string, args ...any) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, timeout)
+}
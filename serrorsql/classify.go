@@ -0,0 +1,92 @@
+package serrorsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// Error codes set as the "code" log attribute of errors by default classifiers.
+const (
+	CodeNoRows               = "no_rows"
+	CodeUniqueViolation      = "unique_violation"
+	CodeForeignKeyViolation  = "foreign_key_violation"
+	CodeDeadlock             = "deadlock"
+	CodeSerializationFailure = "serialization_failure"
+	CodeBadConn              = "bad_conn"
+	CodeTimeout              = "timeout"
+	CodeCanceled             = "canceled"
+)
+
+// Classifier returns the error code of the driver error or an empty
+// string if the error is not recognized.
+type Classifier func(err error) string
+
+// sqlStates maps SQLSTATE codes, reported by PostgreSQL drivers among
+// others, to error codes.
+var sqlStates = map[string]string{
+	"23505": CodeUniqueViolation,
+	"23503": CodeForeignKeyViolation,
+	"40P01": CodeDeadlock,
+	"40001": CodeSerializationFailure,
+	"57014": CodeCanceled,
+}
+
+// messagePatterns maps lower case substrings of error messages of popular
+// drivers to error codes, it is the last resort when drivers don't expose codes.
+var messagePatterns = []struct {
+	substr string
+	code   string
+}{
+	{"duplicate key", CodeUniqueViolation},
+	{"unique constraint", CodeUniqueViolation},
+	{"duplicate entry", CodeUniqueViolation},
+	{"foreign key constraint", CodeForeignKeyViolation},
+	{"deadlock", CodeDeadlock},
+	{"could not serialize access", CodeSerializationFailure},
+}
+
+func classifyStd(err error) string {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return CodeNoRows
+	case errors.Is(err, driver.ErrBadConn):
+		return CodeBadConn
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	}
+	return ""
+}
+
+func classifySQLState(err error) string {
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return sqlStates[e.SQLState()]
+	}
+	return ""
+}
+
+func classifyMessage(err error) string {
+	msg := strings.ToLower(err.Error())
+	for _, p := range messagePatterns {
+		if strings.Contains(msg, p.substr) {
+			return p.code
+		}
+	}
+	return ""
+}
+
+var defaultClassifiers = []Classifier{classifyStd, classifySQLState, classifyMessage}
+
+func classify(classifiers []Classifier, err error) string {
+	for _, c := range classifiers {
+		if code := c(err); code != "" {
+			return code
+		}
+	}
+	return ""
+}
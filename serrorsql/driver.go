@@ -0,0 +1,418 @@
+package serrorsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+type wrappedDriver struct {
+	driver.Driver
+	conf *config
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, d.conf.wrap(err, "error connecting to database")
+	}
+	return &conn{Conn: c, conf: d.conf}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, d.conf.wrap(err, "error opening database")
+		}
+		return &wrappedConnector{Connector: c, conf: d.conf}, nil
+	}
+	return &wrappedConnector{Connector: dsnConnector{dsn: name, driver: d.Driver}, conf: d.conf}, nil
+}
+
+// dsnConnector is the connector of drivers not implementing driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type wrappedConnector struct {
+	driver.Connector
+	conf *config
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, c.conf.wrap(err, "error connecting to database")
+	}
+	return &conn{Conn: dc, conf: c.conf}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return &wrappedDriver{Driver: c.Connector.Driver(), conf: c.conf}
+}
+
+func (c *wrappedConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// conn wraps the driver connection, it is used by one goroutine at a time.
+type conn struct {
+	driver.Conn
+	conf *config
+	// txID is the ID of the transaction in progress, zero if there is none.
+	txID int64
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if cp, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = cp.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, c.conf.wrap(err, "error preparing query", c.conf.attrs(query, nil, 0, -1, c.txID)...)
+	}
+	return &stmt{Stmt: s, conn: c, query: query}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if cb, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		err = errors.New("driver does not support non-default transaction options")
+	} else if err = ctx.Err(); err == nil {
+		tx, err = c.Conn.Begin()
+	}
+
+	txID := c.conf.txID.Add(1)
+	c.conf.log(ctx, "transaction started", c.txAttrs(txID), err)
+	if err != nil {
+		return nil, c.conf.wrap(err, "error starting transaction", c.txAttrs(txID)...)
+	}
+
+	c.txID = txID
+	return &transaction{Tx: tx, conn: c, id: txID}, nil
+}
+
+func (c *conn) txAttrs(txID int64) []any {
+	return c.conf.attrs("", nil, 0, -1, txID)[1:]
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var (
+		start = time.Now()
+		res   driver.Result
+		err   error
+	)
+	switch e := c.Conn.(type) {
+	case driver.ExecerContext:
+		res, err = e.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = e.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	return c.conf.result(ctx, query, args, start, c.txID, res, err)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var (
+		start = time.Now()
+		rows  driver.Rows
+		err   error
+	)
+	switch q := c.Conn.(type) {
+	case driver.QueryerContext:
+		rows, err = q.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = q.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	return c.conf.rows(ctx, query, args, start, c.txID, rows, err)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return c.conf.wrap(p.Ping(ctx), "error pinging database")
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// transaction wraps the driver transaction.
+type transaction struct {
+	driver.Tx
+	conn *conn
+	id   int64
+}
+
+func (t *transaction) Commit() error {
+	t.conn.txID = 0
+	err := t.Tx.Commit()
+	t.conn.conf.log(context.Background(), "transaction committed", t.conn.txAttrs(t.id), err)
+	return t.conn.conf.wrap(err, "error committing transaction", t.conn.txAttrs(t.id)...)
+}
+
+func (t *transaction) Rollback() error {
+	t.conn.txID = 0
+	err := t.Tx.Rollback()
+	t.conn.conf.log(context.Background(), "transaction rolled back", t.conn.txAttrs(t.id), err)
+	return t.conn.conf.wrap(err, "error rolling back transaction", t.conn.txAttrs(t.id)...)
+}
+
+type stmt struct {
+	driver.Stmt
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var (
+		start = time.Now()
+		res   driver.Result
+		err   error
+	)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				res, err = s.Stmt.Exec(values)
+			}
+		}
+	}
+	return s.conn.conf.result(ctx, s.query, args, start, s.conn.txID, res, err)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var (
+		start = time.Now()
+		rows  driver.Rows
+		err   error
+	)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				rows, err = s.Stmt.Query(values)
+			}
+		}
+	}
+	return s.conn.conf.rows(ctx, s.query, args, start, s.conn.txID, rows, err)
+}
+
+func (s *stmt) CheckNamedValue(v *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(v)
+	}
+	return s.conn.CheckNamedValue(v)
+}
+
+func (c *config) result(ctx context.Context, query string, args []driver.NamedValue, start time.Time,
+	txID int64, res driver.Result, err error) (driver.Result, error) {
+
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+
+	var rowsAffected int64 = -1
+	if err == nil {
+		if n, rErr := res.RowsAffected(); rErr == nil {
+			rowsAffected = n
+		}
+	}
+
+	attrs := c.attrs(query, namedValues(args), time.Since(start), rowsAffected, txID)
+	c.log(ctx, "query executed", attrs, err)
+	if err != nil {
+		return nil, c.wrap(err, "error executing query", attrs...)
+	}
+	return res, nil
+}
+
+func (c *config) rows(ctx context.Context, query string, args []driver.NamedValue, start time.Time,
+	txID int64, dr driver.Rows, err error) (driver.Rows, error) {
+
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+
+	attrs := c.attrs(query, namedValues(args), time.Since(start), -1, txID)
+	c.log(ctx, "query executed", attrs, err)
+	if err != nil {
+		return nil, c.wrap(err, "error executing query", attrs...)
+	}
+	return &rows{Rows: dr, conf: c, query: query, args: namedValues(args), txID: txID}, nil
+}
+
+type rows struct {
+	driver.Rows
+	conf  *config
+	query string
+	args  []any
+	txID  int64
+	// n is the number of rows read.
+	n int64
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		attrs := append(r.conf.attrs(r.query, r.args, 0, -1, r.txID), slog.Int64(rowsKey, r.n))
+		return r.conf.wrap(err, "error reading rows", attrs...)
+	}
+	r.n++
+	return nil
+}
+
+func (r *rows) Close() error {
+	attrs := append(r.conf.attrs(r.query, r.args, 0, -1, r.txID), slog.Int64(rowsKey, r.n))
+	return r.conf.wrap(r.Rows.Close(), "error closing rows", attrs...)
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return r.conf.wrap(rs.NextResultSet(), "error reading rows",
+			r.conf.attrs(r.query, r.args, 0, -1, r.txID)...)
+	}
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func namedValues(args []driver.NamedValue) []any {
+	ret := make([]any, len(args))
+	for i, a := range args {
+		ret[i] = a.Value
+	}
+	return ret
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	ret := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("driver does not support the use of named parameters")
+		}
+		ret[i] = a.Value
+	}
+	return ret, nil
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	ret := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		ret[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return ret
+}
@@ -0,0 +1,255 @@
+// Package serrorsql wraps database/sql drivers, so errors returned by them are
+// structured errors carrying the query, redacted arguments, duration and transaction
+// ID as log attributes, and classified into error codes. Executed queries are
+// logged at the debug level with log attributes of the context.
+//
+//	db, err := serrorsql.Open("postgres", dsn)
+//
+//	// Or register the wrapped driver under another name.
+//	sql.Register("serror-postgres", serrorsql.WrapDriver(&pq.Driver{}))
+package serrorsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+)
+
+const (
+	dbKey           = "db"
+	queryKey        = "query"
+	argsKey         = "args"
+	durationKey     = "duration"
+	rowsAffectedKey = "rows_affected"
+	rowsKey         = "rows"
+	txIDKey         = "tx_id"
+	codeKey         = "code"
+
+	redactedValue = "[REDACTED]"
+)
+
+// ArgRedactor returns the query argument value as it should appear in logs.
+type ArgRedactor func(v any) any
+
+type Option func(c *config)
+
+// WithClassifier adds classifiers of driver errors, which are tried in
+// order before default ones recognizing sql.ErrNoRows, SQLSTATE codes and
+// common driver error messages.
+func WithClassifier(classifiers ...Classifier) Option {
+	return func(c *config) {
+		c.classifiers = append(c.classifiers, classifiers...)
+	}
+}
+
+// WithArgRedactor sets the function redacting query arguments. By default,
+// strings and byte slices are replaced with "[REDACTED]", other values are kept.
+func WithArgRedactor(f ArgRedactor) Option {
+	return func(c *config) {
+		c.redact = f
+	}
+}
+
+// WithLogger sets the logger of executed queries, the default logger by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+type config struct {
+	classifiers []Classifier
+	redact      ArgRedactor
+	logger      *slog.Logger
+	txID        atomic.Int64
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		redact: redactStrings,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.classifiers = append(c.classifiers, defaultClassifiers...)
+	return c
+}
+
+func redactStrings(v any) any {
+	switch v.(type) {
+	case string, []byte:
+		return redactedValue
+	}
+	return v
+}
+
+// DB is the sql.DB using the wrapped driver, whose QueryRow methods, also of
+// transactions, wrap sql.ErrNoRows, which is returned by database/sql rather than the driver.
+type DB struct {
+	*sql.DB
+	conf *config
+}
+
+// Open opens the database like sql.Open, wrapping the registered driver.
+func Open(driverName, dataSourceName string, opts ...Option) (*DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, serror.Wrap(err, "error opening database", slog.String("driver", driverName))
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	var c driver.Connector
+	if dc, ok := d.(driver.DriverContext); ok {
+		if c, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, serror.Wrap(err, "error opening database", slog.String("driver", driverName))
+		}
+	} else {
+		c = dsnConnector{dsn: dataSourceName, driver: d}
+	}
+
+	conf := newConfig(opts)
+	return &DB{
+		DB:   sql.OpenDB(&wrappedConnector{Connector: c, conf: conf}),
+		conf: conf,
+	}, nil
+}
+
+// QueryRowContext executes the query expected to return at most one row.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return &Row{
+		Row:   db.DB.QueryRowContext(ctx, query, args...),
+		query: query,
+		args:  args,
+		conf:  db.conf,
+	}
+}
+
+// QueryRow executes the query expected to return at most one row.
+func (db *DB) QueryRow(query string, args ...any) *Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// BeginTx starts the transaction, see sql.DB.BeginTx.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, conf: db.conf}, nil
+}
+
+// Begin starts the transaction, see sql.DB.Begin.
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// Tx is the sql.Tx whose QueryRow methods wrap sql.ErrNoRows.
+type Tx struct {
+	*sql.Tx
+	conf *config
+}
+
+// QueryRowContext executes the query expected to return at most one row.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return &Row{
+		Row:   tx.Tx.QueryRowContext(ctx, query, args...),
+		query: query,
+		args:  args,
+		conf:  tx.conf,
+	}
+}
+
+// QueryRow executes the query expected to return at most one row.
+func (tx *Tx) QueryRow(query string, args ...any) *Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+// Row is the sql.Row whose Scan wraps sql.ErrNoRows.
+type Row struct {
+	*sql.Row
+	query string
+	args  []any
+	conf  *config
+}
+
+// Scan copies columns of the row into dest, see sql.Row.Scan.
+func (r *Row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.conf.wrap(err, "error querying row", r.conf.attrs(r.query, r.args, 0, -1, 0)...)
+	}
+	return err
+}
+
+// WrapDriver returns the driver wrapping errors of the driver d.
+func WrapDriver(d driver.Driver, opts ...Option) driver.Driver {
+	return &wrappedDriver{Driver: d, conf: newConfig(opts)}
+}
+
+// WrapConnector returns the connector wrapping errors of connections of the connector c,
+// use it with sql.OpenDB.
+func WrapConnector(c driver.Connector, opts ...Option) driver.Connector {
+	return &wrappedConnector{Connector: c, conf: newConfig(opts)}
+}
+
+// wrap returns the structured error with database attributes and the error code.
+// Errors database/sql expects from drivers as is are not wrapped.
+func (c *config) wrap(err error, msg string, attrs ...any) error {
+	if err == nil || err == driver.ErrSkip || err == driver.ErrRemoveArgument || err == io.EOF {
+		return err
+	}
+
+	args := []any{slog.Group(dbKey, attrs...)}
+	if code := classify(c.classifiers, err); code != "" {
+		args = append(args, slog.String(codeKey, code))
+	}
+	return serror.Wrap(err, msg, args...)
+}
+
+// attrs returns attributes of the query, negative rows affected are omitted.
+func (c *config) attrs(query string, args []any, d time.Duration, rowsAffected, txID int64) []any {
+	attrs := []any{slog.String(queryKey, query)}
+	if len(args) > 0 && c.redact != nil {
+		redacted := make([]any, len(args))
+		for i, a := range args {
+			redacted[i] = c.redact(a)
+		}
+		attrs = append(attrs, slog.Any(argsKey, redacted))
+	}
+	if d > 0 {
+		attrs = append(attrs, slog.Duration(durationKey, d))
+	}
+	if rowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64(rowsAffectedKey, rowsAffected))
+	}
+	if txID > 0 {
+		attrs = append(attrs, slog.Int64(txIDKey, txID))
+	}
+	return attrs
+}
+
+// log logs the executed query at the debug level.
+func (c *config) log(ctx context.Context, msg string, attrs []any, err error) {
+	logger := c.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	args := []any{slog.Group(dbKey, attrs...), loghelper.Attr(ctx)}
+	if err != nil {
+		args = append(args, slog.String("error", err.Error()))
+	}
+	logger.DebugContext(ctx, msg, args...)
+}
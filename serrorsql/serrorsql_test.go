@@ -0,0 +1,286 @@
+package serrorsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/loghelper"
+	"github.com/vovanec/serror/loghelper/logtest"
+	"github.com/vovanec/serror/serrorsql"
+	"github.com/vovanec/serror/serrortest"
+)
+
+func init() {
+	sql.Register("fake", fakeDriver{})
+}
+
+// fakeDriver is the in-memory driver behaving according to query text.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "syntax error") {
+		return nil, errors.New("syntax error at or near \"error\"")
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "prepared") {
+		return nil, driver.ErrSkip
+	}
+	return exec(query, len(args))
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "deadlock"):
+		return nil, stateError("40P01")
+	case strings.Contains(query, "none"):
+		return &fakeRows{}, nil
+	case strings.Contains(query, "broken"):
+		return &fakeRows{rows: [][]driver.Value{{int64(1), "a"}}, err: errors.New("connection reset by peer")}, nil
+	case strings.Contains(query, "close"):
+		return &fakeRows{closeErr: errors.New("connection reset by peer")}, nil
+	}
+	return &fakeRows{rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}}, nil
+}
+
+func exec(query string, n int) (driver.Result, error) {
+	if strings.Contains(query, "duplicate") {
+		return nil, errors.New("ERROR: duplicate key value violates unique constraint \"users_pkey\"")
+	}
+	return driver.RowsAffected(n), nil
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return exec(s.query, len(args))
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeRows struct {
+	rows     [][]driver.Value
+	err      error
+	closeErr error
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "name"}
+}
+
+func (r *fakeRows) Close() error {
+	return r.closeErr
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type stateError string
+
+func (e stateError) Error() string {
+	return "database error " + string(e)
+}
+
+func (e stateError) SQLState() string {
+	return string(e)
+}
+
+func openDB(t *testing.T, opts ...serrorsql.Option) *serrorsql.DB {
+	t.Helper()
+
+	db, err := serrorsql.Open("fake", "", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestExec(t *testing.T) {
+	var (
+		logs = logtest.Capture(t)
+		db   = openDB(t, serrorsql.WithLogger(slog.New(logs)))
+		ctx  = loghelper.Context(context.Background(), slog.String("request_id", "42"))
+	)
+
+	res, err := db.ExecContext(ctx, "INSERT INTO users VALUES (?, ?)", 1, "vovan")
+	require.NoError(t, err)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	logs.AssertLogged(t, slog.LevelDebug, "query executed",
+		"db.query", "INSERT INTO users VALUES (?, ?)",
+		"db.rows_affected", int64(2),
+		"request_id", "42",
+	)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO users VALUES (?, ?) -- duplicate", 1, "vovan")
+	require.Error(t, err)
+	serrortest.AssertCode(t, err, serrorsql.CodeUniqueViolation)
+	serrortest.AssertHasAttr(t, err, "db.query", "INSERT INTO users VALUES (?, ?) -- duplicate")
+	serrortest.AssertHasAttr(t, err, "db.args", []any{int64(1), "[REDACTED]"})
+	assert.Contains(t, serrortest.AttrsOf(err), "db.duration")
+	assert.NotContains(t, serrortest.AttrsOf(err), "request_id")
+	assert.Equal(t, "error executing query: ERROR: duplicate key value violates unique constraint \"users_pkey\"",
+		err.Error())
+
+	// Queries the driver can't execute directly are executed with prepared statements.
+	_, err = db.ExecContext(ctx, "INSERT INTO users VALUES (?) -- prepared duplicate", "vovan")
+	serrortest.AssertCode(t, err, serrorsql.CodeUniqueViolation)
+	assert.Len(t, logs.ByAttr("db.query", "INSERT INTO users VALUES (?) -- prepared duplicate"), 1)
+
+	_, err = db.ExecContext(ctx, "prepared syntax error")
+	serrortest.AssertHasAttr(t, err, "db.query", "prepared syntax error")
+	assert.Empty(t, serror.Code(err))
+}
+
+func TestQuery(t *testing.T) {
+	db := openDB(t, serrorsql.WithArgRedactor(func(v any) any { return v }))
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(new(int), &name))
+	assert.Equal(t, "a", name)
+
+	err := db.QueryRow("SELECT none FROM users WHERE id = ?", "vovan").Scan(new(int), &name)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	serrortest.AssertCode(t, err, serrorsql.CodeNoRows)
+	serrortest.AssertHasAttr(t, err, "db.args", []any{"vovan"})
+
+	_, err = db.Query("SELECT deadlock")
+	serrortest.AssertCode(t, err, serrorsql.CodeDeadlock)
+
+	rows, err := db.Query("SELECT broken")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	err = rows.Err()
+	require.Error(t, err)
+	serrortest.AssertHasAttr(t, err, "db.rows", int64(1))
+	serrortest.AssertHasAttr(t, err, "db.query", "SELECT broken")
+	require.NoError(t, rows.Close())
+
+	rows, err = db.Query("SELECT close")
+	require.NoError(t, err)
+	err = rows.Close()
+	require.Error(t, err)
+	serrortest.AssertHasAttr(t, err, "db.query", "SELECT close")
+}
+
+func TestTransaction(t *testing.T) {
+	db := openDB(t)
+
+	for i := 1; i <= 2; i++ {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		_, err = tx.Exec("INSERT INTO users VALUES (1) -- duplicate")
+		serrortest.AssertHasAttr(t, err, "db.tx_id", int64(i))
+		err = tx.QueryRow("SELECT none FROM users WHERE id = ?", 1).Scan(new(int), new(string))
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		serrortest.AssertCode(t, err, serrorsql.CodeNoRows)
+		require.NoError(t, tx.Rollback())
+	}
+
+	_, err := db.Exec("INSERT INTO users VALUES (1) -- duplicate")
+	assert.NotContains(t, serrortest.AttrsOf(err), "db.tx_id")
+}
+
+func TestClassifier(t *testing.T) {
+	db := openDB(t, serrorsql.WithClassifier(func(err error) string {
+		var se stateError
+		if errors.As(err, &se) && se == "40P01" {
+			return "retry"
+		}
+		return ""
+	}))
+
+	_, err := db.Query("SELECT deadlock")
+	serrortest.AssertCode(t, err, "retry")
+
+	_, err = db.Exec("INSERT INTO users VALUES (1) -- duplicate")
+	serrortest.AssertCode(t, err, serrorsql.CodeUniqueViolation)
+}
+
+func TestWrapDriver(t *testing.T) {
+	sql.Register("fake-wrapped", serrorsql.WrapDriver(fakeDriver{}))
+
+	db, err := sql.Open("fake-wrapped", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO users VALUES (1) -- duplicate")
+	serrortest.AssertCode(t, err, serrorsql.CodeUniqueViolation)
+
+	db = sql.OpenDB(serrorsql.WrapConnector(driverConnector{}))
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO users VALUES (1) -- duplicate")
+	serrortest.AssertCode(t, err, serrorsql.CodeUniqueViolation)
+
+	_, err = serrorsql.Open("unknown", "")
+	assert.Error(t, err)
+}
+
+type driverConnector struct{}
+
+func (driverConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+func (driverConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
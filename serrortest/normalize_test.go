@@ -0,0 +1,13 @@
+package serrortest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeStack(t *testing.T) {
+	assert.Equal(t, "a.go b.go", normalizeStack("/src/my app/a.go:12 /src/lib/b.go:7"))
+	assert.Equal(t, "a b.go", normalizeStack("/src/a b.go:1"))
+	assert.Empty(t, normalizeStack(""))
+}
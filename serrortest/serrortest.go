@@ -0,0 +1,230 @@
+// Package serrortest provides helpers for asserting on structured errors in tests.
+package serrortest
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/vovanec/serror"
+)
+
+const (
+	errKey         = "error"
+	stackKey       = "stack"
+	sourceKey      = "source"
+	fingerprintKey = "fingerprint"
+)
+
+var update = flag.Bool("serrortest.update", false, "update golden files")
+
+// AttrsOf returns log attributes attached to the error, keyed by dot separated group path,
+// e.g. "db.query". Attributes of the error group (message, stack) are not included.
+func AttrsOf(err error) map[string]any {
+	ret := make(map[string]any)
+
+	var lv slog.LogValuer
+	if !errors.As(err, &lv) {
+		return ret
+	}
+
+	v := lv.LogValue().Resolve()
+	if v.Kind() != slog.KindGroup {
+		return ret
+	}
+
+	for _, a := range v.Group() {
+		if a.Key == errKey {
+			continue
+		}
+		flattenAttr("", a, ret)
+	}
+	return ret
+}
+
+func flattenAttr(prefix string, a slog.Attr, m map[string]any) {
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + a.Key
+	}
+
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key == "" {
+			key = prefix
+		}
+		for _, ga := range v.Group() {
+			flattenAttr(key, ga, m)
+		}
+		return
+	}
+	m[key] = v.Any()
+}
+
+// AssertHasAttr asserts that the error has the log attribute with the given
+// dot separated key and value.
+func AssertHasAttr(t testing.TB, err error, key string, value any) bool {
+	t.Helper()
+
+	attrs := AttrsOf(err)
+	got, ok := attrs[key]
+	if !ok {
+		t.Errorf("error %q has no attribute %q, attributes: %v", err, key, attrs)
+		return false
+	}
+	if !valuesEqual(slog.AnyValue(value), slog.AnyValue(got)) {
+		t.Errorf("error %q attribute %q: got %v (%T), want %v (%T)", err, key, got, got, value, value)
+		return false
+	}
+	return true
+}
+
+// valuesEqual compares values, values of non-comparable types like slices are compared deeply.
+func valuesEqual(a, b slog.Value) bool {
+	if a.Kind() == slog.KindAny && b.Kind() == slog.KindAny {
+		return reflect.DeepEqual(a.Any(), b.Any())
+	}
+	return a.Equal(b)
+}
+
+// AssertCode asserts that the error has the given code.
+func AssertCode(t testing.TB, err error, code string) bool {
+	t.Helper()
+
+	if got := serror.Code(err); got != code {
+		t.Errorf("error %q code: got %q, want %q", err, got, code)
+		return false
+	}
+	return true
+}
+
+// AssertOrigin asserts that the error originated at the given line
+// of the file, the file is matched by the path suffix.
+func AssertOrigin(t testing.TB, err error, file string, line int) bool {
+	t.Helper()
+
+	var eo serror.ErrorOrigin
+	if !errors.As(err, &eo) {
+		t.Errorf("error %q has no origin", err)
+		return false
+	}
+
+	o := eo.Origin()
+	if !pathHasSuffix(o.File, file) || o.Line != line {
+		t.Errorf("error %q origin: got %s, want %s:%d", err, o, file, line)
+		return false
+	}
+	return true
+}
+
+// AssertGolden asserts that the error log value rendered as JSON matches the content of the golden
+// file. Stack and fingerprint are normalized and source lines are left out, so golden files do not
+// depend on the source location.
+// Run tests with -serrortest.update flag to write golden files.
+func AssertGolden(t testing.TB, err error, golden string) bool {
+	t.Helper()
+
+	got, mErr := json.MarshalIndent(normalize(logValue(err), nil), "", "  ")
+	if mErr != nil {
+		t.Errorf("error marshalling error log value: %v", mErr)
+		return false
+	}
+	got = append(got, '\n')
+
+	if *update {
+		wErr := os.MkdirAll(filepath.Dir(golden), 0o755)
+		if wErr == nil {
+			wErr = os.WriteFile(golden, got, 0o644)
+		}
+		if wErr != nil {
+			t.Errorf("error updating golden file %s: %v", golden, wErr)
+			return false
+		}
+	}
+
+	want, rErr := os.ReadFile(golden)
+	if rErr != nil {
+		t.Errorf("error reading golden file %s: %v", golden, rErr)
+		return false
+	}
+	if string(want) != string(got) {
+		t.Errorf("error log value does not match golden file %s:\ngot:\n%s\nwant:\n%s", golden, got, want)
+		return false
+	}
+	return true
+}
+
+func logValue(err error) slog.Value {
+	var lv slog.LogValuer
+	if errors.As(err, &lv) {
+		return lv.LogValue().Resolve()
+	}
+	return slog.GroupValue(slog.Group(errKey, slog.String("msg", err.Error())))
+}
+
+// normalize converts the value to the JSON-friendly form, replacing
+// source locations of the error stack with file names and dropping source lines.
+func normalize(v slog.Value, groups []string) any {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		m := make(map[string]any)
+		for _, a := range v.Group() {
+			if len(groups) == 1 && groups[0] == errKey && a.Key == sourceKey {
+				continue
+			}
+			val := normalize(a.Value, append(groups, a.Key))
+			if len(groups) == 1 && groups[0] == errKey {
+				switch a.Key {
+				case stackKey:
+					val = normalizeStack(a.Value.String())
+				case fingerprintKey:
+					val = "<fingerprint>"
+				}
+			}
+			m[a.Key] = val
+		}
+		return m
+	case slog.KindDuration, slog.KindTime:
+		return v.String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return v.Any()
+}
+
+// originEnd matches the line number ending the origin in the stack.
+var originEnd = regexp.MustCompile(`:\d+( |$)`)
+
+// normalizeStack replaces file:line origins of the space separated stack with file names,
+// the stack is split after line numbers as file paths may contain spaces.
+func normalizeStack(stack string) string {
+	var (
+		ret   []string
+		start int
+	)
+	for _, loc := range originEnd.FindAllStringIndex(stack, -1) {
+		ret = append(ret, path.Base(stack[start:loc[0]]))
+		start = loc[1]
+	}
+	return strings.Join(ret, " ")
+}
+
+func pathHasSuffix(p, suffix string) bool {
+	p, suffix = filepath.ToSlash(p), filepath.ToSlash(suffix)
+	return p == suffix || strings.HasSuffix(p, "/"+suffix)
+}
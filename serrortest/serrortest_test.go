@@ -0,0 +1,66 @@
+package serrortest_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vovanec/serror"
+	"github.com/vovanec/serror/serrortest"
+)
+
+func getUser(id string) error {
+	return serror.New("user not found",
+		slog.String("code", "not_found"),
+		slog.String("user_id", id),
+		slog.Group("db", slog.String("query", "SELECT * FROM users WHERE id=$1")),
+	)
+}
+
+// failingT records assertion failures instead of failing the test.
+type failingT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *failingT) Helper() {}
+
+func (t *failingT) Errorf(string, ...any) {
+	t.failed = true
+}
+
+func TestAttrsOf(t *testing.T) {
+	err := serror.Wrap(getUser("1"), "error handling request", slog.Int("attempt", 2))
+
+	assert.Equal(t, map[string]any{
+		"attempt":  int64(2),
+		"code":     "not_found",
+		"user_id":  "1",
+		"db.query": "SELECT * FROM users WHERE id=$1",
+	}, serrortest.AttrsOf(err))
+	assert.Empty(t, serrortest.AttrsOf(nil))
+
+	serrortest.AssertHasAttr(t, serror.New("error", slog.Any("ids", []int{1, 2})), "ids", []int{1, 2})
+	serrortest.AssertHasAttr(t, serror.New("error", slog.Any("labels", map[string]string{"a": "b"})),
+		"labels", map[string]string{"a": "b"})
+
+	serrortest.AssertHasAttr(t, err, "attempt", 2)
+	serrortest.AssertHasAttr(t, err, "db.query", "SELECT * FROM users WHERE id=$1")
+	serrortest.AssertCode(t, err, "not_found")
+	serrortest.AssertOrigin(t, err, "serrortest/serrortest_test.go", 13)
+	serrortest.AssertGolden(t, err, "testdata/get_user.golden")
+
+	// Source lines depend on the source location and are not part of golden files.
+	serror.EnableSourceContext(1)
+	serrortest.AssertGolden(t, err, "testdata/get_user.golden")
+	serror.EnableSourceContext(0)
+
+	ft := &failingT{TB: t}
+	assert.False(t, serrortest.AssertHasAttr(ft, err, "attempt", 3))
+	assert.False(t, serrortest.AssertHasAttr(ft, err, "missing", 3))
+	assert.False(t, serrortest.AssertHasAttr(ft, err, "attempt", []int{2}))
+	assert.False(t, serrortest.AssertHasAttr(ft, serror.New("error", slog.Any("ids", []int{1, 2})), "ids", []int{2, 1}))
+	assert.False(t, serrortest.AssertCode(ft, err, "internal"))
+	assert.False(t, serrortest.AssertOrigin(ft, err, "serrortest_test.go", 1))
+	assert.True(t, ft.failed)
+}
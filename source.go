@@ -0,0 +1,250 @@
+package serror
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	srcKey = "source"
+
+	// maxSourceLineLen is the maximal length of the source line, longer lines are truncated.
+	maxSourceLineLen = 256
+)
+
+// SourceLine is the line of source code.
+type SourceLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// SourceContext is the source code surrounding the error origin.
+type SourceContext struct {
+	File  string       `json:"file"`
+	Line  int          `json:"line"`
+	Lines []SourceLine `json:"lines"`
+}
+
+type SourceOption func(c *sourceConfig)
+
+// WithSourceFS sets the file system source files are read from, e.g. embed.FS with the module
+// sources, instead of the local file system. Files are looked up by the longest suffix of the
+// origin path with at least one directory which exists in the file system, so sources embedded
+// in the module root package are found by paths relative to the module root. Files of the main
+// module root package are looked up by their names.
+func WithSourceFS(fsys fs.FS) SourceOption {
+	return func(c *sourceConfig) {
+		c.fsys = fsys
+	}
+}
+
+// WithSourceCacheSize sets the maximal total size in bytes of cached source files, 4 MiB by default.
+// Least recently used files are evicted from the cache, files larger than the limit are never read.
+func WithSourceCacheSize(size int) SourceOption {
+	return func(c *sourceConfig) {
+		c.cacheSize = size
+	}
+}
+
+type sourceConfig struct {
+	fsys      fs.FS
+	cacheSize int
+}
+
+var sourceCtx atomic.Pointer[sourceReader]
+
+// EnableSourceContext adds the given number of source lines before and after every origin
+// of the error stack to the error log value as error.source attribute and to the %+v output.
+// Source files must be available at run time, e.g. in development builds, or embedded and
+// passed with WithSourceFS. EnableSourceContext(0) disables adding source lines.
+func EnableSourceContext(lines int, opts ...SourceOption) {
+	if lines < 1 {
+		sourceCtx.Store(nil)
+		return
+	}
+
+	conf := sourceConfig{
+		cacheSize: 4 << 20,
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	var module string
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		module = bi.Main.Path
+	}
+
+	sourceCtx.Store(&sourceReader{
+		conf:   conf,
+		lines:  lines,
+		module: module,
+		cache:  make(map[string]*list.Element),
+		lru:    list.New(),
+	})
+}
+
+// sourceContexts returns source contexts of the stack origins, or nil if adding
+// source lines is disabled or no sources are available.
+func sourceContexts(stack StackTrace) []SourceContext {
+	r := sourceCtx.Load()
+	if r == nil {
+		return nil
+	}
+
+	var ret []SourceContext
+	for _, o := range stack {
+		if sc, ok := r.context(o); ok {
+			ret = append(ret, sc)
+		}
+	}
+	return ret
+}
+
+// writeSourceContexts writes source contexts marking origin lines.
+func writeSourceContexts(w io.Writer, contexts []SourceContext) {
+	for _, sc := range contexts {
+		_, _ = fmt.Fprintf(w, "\n%s:%d", sc.File, sc.Line)
+		for _, l := range sc.Lines {
+			marker := " "
+			if l.Line == sc.Line {
+				marker = ">"
+			}
+			_, _ = fmt.Fprintf(w, "\n%s %5d  %s", marker, l.Line, l.Text)
+		}
+	}
+}
+
+// sourceReader reads source files into the LRU cache limited by size.
+type sourceReader struct {
+	conf   sourceConfig
+	lines  int
+	module string
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List
+	size  int
+}
+
+type sourceFile struct {
+	name  string
+	lines []string
+	size  int
+}
+
+func (r *sourceReader) context(o Origin) (SourceContext, bool) {
+	if o.Empty() {
+		return SourceContext{}, false
+	}
+
+	lines := r.file(o)
+	if o.Line < 1 || o.Line > len(lines) {
+		return SourceContext{}, false
+	}
+
+	sc := SourceContext{
+		File: o.File,
+		Line: o.Line,
+	}
+	for n := max(o.Line-r.lines, 1); n <= min(o.Line+r.lines, len(lines)); n++ {
+		text := lines[n-1]
+		if len(text) > maxSourceLineLen {
+			text = text[:maxSourceLineLen]
+		}
+		sc.Lines = append(sc.Lines, SourceLine{Line: n, Text: text})
+	}
+	return sc, true
+}
+
+// file returns lines of the origin file, or nil if the file is not available.
+func (r *sourceReader) file(o Origin) []string {
+	name := o.File
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.cache[name]; ok {
+		r.lru.MoveToFront(el)
+		return el.Value.(*sourceFile).lines
+	}
+
+	// Missing files are cached too, so they are not looked up again.
+	f := &sourceFile{name: name, size: len(name)}
+	if data, ok := r.read(o, r.conf.cacheSize-f.size); ok {
+		f.lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		f.size += len(data)
+	}
+
+	r.cache[name] = r.lru.PushFront(f)
+	r.size += f.size
+	for r.size > r.conf.cacheSize {
+		el := r.lru.Back()
+		evicted := r.lru.Remove(el).(*sourceFile)
+		delete(r.cache, evicted.name)
+		r.size -= evicted.size
+	}
+	return f.lines
+}
+
+// read reads the origin source file from the local file system or the configured FS,
+// files larger than the limit are not read.
+func (r *sourceReader) read(o Origin, limit int) ([]byte, bool) {
+	if r.conf.fsys == nil {
+		fi, err := os.Stat(o.File)
+		if err != nil || fi.IsDir() || fi.Size() > int64(limit) {
+			return nil, false
+		}
+		data, err := os.ReadFile(o.File)
+		return data, err == nil
+	}
+
+	// The origin path is absolute, or module path based if the binary was built
+	// with -trimpath, so it is matched by its longest suffix present in the FS.
+	// Suffixes without a directory would match files of the same name in any package.
+	p := strings.TrimPrefix(filepath.ToSlash(o.File), "/")
+	for {
+		i := strings.Index(p, "/")
+		if i < 0 {
+			break
+		}
+		if data, ok := readSource(r.conf.fsys, p, limit); ok {
+			return data, true
+		}
+		p = p[i+1:]
+	}
+	if r.module == "" || funcPackage(o.Function) != r.module {
+		return nil, false
+	}
+	return readSource(r.conf.fsys, p, limit)
+}
+
+// funcPackage returns the package path of the qualified function name, e.g. "github.com/a/b.(*T).M".
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// readSource reads the file if it is not larger than the limit.
+func readSource(fsys fs.FS, name string, limit int) ([]byte, bool) {
+	if !fs.ValidPath(name) {
+		return nil, false
+	}
+	fi, err := fs.Stat(fsys, name)
+	if err != nil || fi.IsDir() || fi.Size() > int64(limit) {
+		return nil, false
+	}
+	data, err := fs.ReadFile(fsys, name)
+	return data, err == nil
+}
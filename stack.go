@@ -17,18 +17,23 @@ func (st StackTrace) String() string {
 }
 
 func getOrigin(n int) Origin {
-	if _, file, line, ok := runtime.Caller(n); ok {
-		return Origin{
+	if pc, file, line, ok := runtime.Caller(n); ok {
+		o := Origin{
 			Line: line,
 			File: file,
 		}
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			o.Function = fn.Name()
+		}
+		return o
 	}
 	return Origin{}
 }
 
 type Origin struct {
-	Line int
-	File string
+	Line     int
+	File     string
+	Function string
 }
 
 func (o Origin) String() string {
@@ -0,0 +1,187 @@
+package serror
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/vovanec/serror/internal"
+)
+
+// Newt returns an error with the message rendered from the template, where {key}
+// placeholders are replaced with values of log attributes with the same keys.
+// Attributes of groups are addressed with dot separated keys, like {user.id}, and
+// "{{" and "}}" stand for literal braces. Placeholders without attributes are left
+// as is. The raw template is used for fingerprinting instead of the rendered message,
+// and it is logged as the error.template attribute.
+//
+//	serror.Newt("user {user_id} not found in {table}",
+//		slog.Int("user_id", id),
+//		slog.String("table", "users"),
+//	)
+func Newt(template string, args ...any) error {
+	return newError(1, nil, template, true, args)
+}
+
+// NewtDepth is like Newt, but the error origin is taken depth stack frames above
+// the caller, see NewDepth.
+func NewtDepth(depth int, template string, args ...any) error {
+	return newError(depth+1, nil, template, true, args)
+}
+
+// TemplateError is returned by ValidateTemplate when the template placeholders
+// don't match the log attributes.
+type TemplateError struct {
+	Template string
+	// Missing are placeholders without log attributes.
+	Missing []string
+	// Extra are keys of log attributes not used by placeholders.
+	Extra []string
+}
+
+func (e *TemplateError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing attributes for placeholders: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Extra) > 0 {
+		parts = append(parts, "attributes without placeholders: "+strings.Join(e.Extra, ", "))
+	}
+	return fmt.Sprintf("invalid message template %q: %s", e.Template, strings.Join(parts, "; "))
+}
+
+// ValidateTemplate checks that every placeholder of the template has a log attribute
+// among args and every log attribute is used by a placeholder. It returns *TemplateError
+// listing mismatched keys, or nil if the template and args match. Note that attributes
+// of contexts passed in args are taken into account as well.
+func ValidateTemplate(template string, args ...any) error {
+
+	am := make(map[string]slog.Attr)
+	internal.ParseLogArgs(args, func(a slog.Attr) {
+		am[a.Key] = a
+	})
+
+	var (
+		tErr = TemplateError{Template: template}
+		used = make(map[string]bool)
+	)
+	parseTemplate(template, func(_, key string) {
+		if key == "" || used[key] {
+			return
+		}
+		used[key] = true
+		if _, ok := lookupAttr(am, key); !ok {
+			tErr.Missing = append(tErr.Missing, key)
+		}
+	})
+
+	for k := range am {
+		if !used[k] && !usedPrefix(used, k) {
+			tErr.Extra = append(tErr.Extra, k)
+		}
+	}
+	sort.Strings(tErr.Extra)
+
+	if len(tErr.Missing) > 0 || len(tErr.Extra) > 0 {
+		return &tErr
+	}
+	return nil
+}
+
+// usedPrefix reports whether any attribute of the group is used by a placeholder.
+func usedPrefix(used map[string]bool, group string) bool {
+	for k := range used {
+		if strings.HasPrefix(k, group+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func renderTemplate(template string, am map[string]slog.Attr) string {
+	var b strings.Builder
+	parseTemplate(template, func(literal, key string) {
+		if key == "" {
+			b.WriteString(literal)
+			return
+		}
+		if a, ok := lookupAttr(am, key); ok {
+			b.WriteString(a.Value.String())
+			return
+		}
+		b.WriteString(literal)
+	})
+	return b.String()
+}
+
+// parseTemplate calls f for every literal text, with empty key, and every
+// placeholder, with its text including braces and its key.
+func parseTemplate(template string, f func(literal, key string)) {
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			f(literal.String(), "")
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c:
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i+1:], '}')
+			if end < 0 {
+				literal.WriteString(template[i:])
+				i = len(template)
+				continue
+			}
+			key := template[i+1 : i+1+end]
+			if key == "" || strings.ContainsAny(key, "{ \t\n") {
+				literal.WriteByte(c)
+				continue
+			}
+			flush()
+			f(template[i:i+end+2], key)
+			i += end + 1
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+}
+
+// lookupAttr returns the attribute by the dot separated key.
+func lookupAttr(am map[string]slog.Attr, key string) (slog.Attr, bool) {
+	if a, ok := am[key]; ok {
+		return resolveAttr(a), true
+	}
+
+	head, path, _ := strings.Cut(key, ".")
+	a, ok := am[head]
+	for ok && path != "" {
+		head, path, _ = strings.Cut(path, ".")
+		ok = false
+		a = resolveAttr(a)
+		if a.Value.Kind() == slog.KindGroup {
+			for _, ga := range a.Value.Group() {
+				if ga.Key == head {
+					a, ok = ga, true
+					break
+				}
+			}
+		}
+	}
+	if !ok {
+		return slog.Attr{}, false
+	}
+	return resolveAttr(a), true
+}
+
+func resolveAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	return a
+}